@@ -3,7 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestLoadProductionConfig(t *testing.T) {
@@ -20,3 +22,97 @@ func TestLoadProductionConfig(t *testing.T) {
 
 	log.Printf("Config: %+v", cfg.Database)
 }
+
+func TestLoadSearchConfig(t *testing.T) {
+	t.Setenv("APP_ENV", "test")
+	t.Setenv("SEARCH_MIN_SIMILARITY", "0.45")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Search.MinSimilarity != 0.45 {
+		t.Errorf("Expected search min similarity to be 0.45, got %v", cfg.Search.MinSimilarity)
+	}
+
+	t.Setenv("SEARCH_MIN_SIMILARITY", "not-a-number")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Search.MinSimilarity != 0.3 {
+		t.Errorf("Expected search min similarity to fall back to 0.3, got %v", cfg.Search.MinSimilarity)
+	}
+}
+
+func TestLoadInventoryConfig(t *testing.T) {
+	t.Setenv("APP_ENV", "test")
+	t.Setenv("RESERVATION_TTL", "90s")
+	t.Setenv("RESERVATION_SWEEP_INTERVAL", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Inventory.ReservationTTL != 90*time.Second {
+		t.Errorf("Expected reservation TTL to be 90s, got %v", cfg.Inventory.ReservationTTL)
+	}
+	if cfg.Inventory.SweepInterval != time.Minute {
+		t.Errorf("Expected sweep interval to default to 1m, got %v", cfg.Inventory.SweepInterval)
+	}
+
+	t.Setenv("RESERVATION_TTL", "-5m")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Inventory.ReservationTTL != 15*time.Minute {
+		t.Errorf("Expected reservation TTL to fall back to 15m, got %v", cfg.Inventory.ReservationTTL)
+	}
+}
+
+func TestLoadLocaleConfig(t *testing.T) {
+	t.Setenv("APP_ENV", "test")
+	t.Setenv("DEFAULT_LOCALE", "fr")
+	t.Setenv("SUPPORTED_LOCALES", "en, fr,,de")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Locale.Default != "fr" {
+		t.Errorf("Expected default locale to be fr, got %q", cfg.Locale.Default)
+	}
+	if !slices.Equal(cfg.Locale.Supported, []string{"fr", "en", "de"}) {
+		t.Errorf("Expected supported locales to be [fr en de], got %v", cfg.Locale.Supported)
+	}
+}
+
+func TestLoadReviewsConfig(t *testing.T) {
+	t.Setenv("APP_ENV", "test")
+	t.Setenv("REVIEWS_AUTO_APPROVE", "false")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Reviews.AutoApprove {
+		t.Errorf("Expected reviews not to be auto approved")
+	}
+
+	t.Setenv("REVIEWS_AUTO_APPROVE", "maybe")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Reviews.AutoApprove {
+		t.Errorf("Expected reviews auto approval to fall back to false")
+	}
+}
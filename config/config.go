@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -19,6 +22,14 @@ type Config struct {
 	Server      ServerConfig
 	Database    DatabaseConfig
 	Firebase    FirebaseConfig
+	Search      SearchConfig
+	Inventory   InventoryConfig
+	Pricing     PricingConfig
+	Storage     StorageConfig
+	Related     RelatedConfig
+	Locale      LocaleConfig
+	Ratings     RatingsConfig
+	Reviews     ReviewsConfig
 }
 
 type ServerConfig struct {
@@ -38,6 +49,60 @@ type FirebaseConfig struct {
 	CredentialsFile string
 }
 
+type InventoryConfig struct {
+	// ReservationTTL is how long reserved stock is held before it is released
+	ReservationTTL time.Duration
+	// SweepInterval is how often expired reservations are deleted
+	SweepInterval time.Duration
+}
+
+type PricingConfig struct {
+	// ScheduleInterval is how often due scheduled price changes are applied
+	ScheduleInterval time.Duration
+}
+
+type StorageConfig struct {
+	// Dir is the directory uploaded files are stored in
+	Dir string
+	// MaxImageSize is the largest accepted image upload, in bytes
+	MaxImageSize int64
+	// ProcessInterval is how often new images are picked up for resizing
+	ProcessInterval time.Duration
+}
+
+type LocaleConfig struct {
+	// Default is the locale of the names and descriptions stored on products
+	Default string
+	// Supported are the locales translations can be stored and served in,
+	// the default locale always comes first
+	Supported []string
+}
+
+type RelatedConfig struct {
+	// RefreshInterval is how often related products are recomputed from reviews
+	RefreshInterval time.Duration
+}
+
+type RatingsConfig struct {
+	// PriorMean is the rating a product is assumed to have before it is
+	// reviewed, the Bayesian score of a product starts there
+	PriorMean float64
+	// PriorWeight is how many reviews the prior mean counts as
+	PriorWeight float64
+}
+
+type ReviewsConfig struct {
+	// AutoApprove publishes new and edited reviews right away, otherwise they
+	// wait in the moderation queue until an admin approves them
+	AutoApprove bool
+}
+
+type SearchConfig struct {
+	// MinSimilarity is the pg_trgm word similarity (0 to 1) a product name
+	// needs to reach to be returned as a typeahead suggestion
+	MinSimilarity float64
+}
+
 // DBSecret represents the structure of the database secret in AWS Secrets Manager
 type DBSecret struct {
 	Host     string `json:"host"`
@@ -64,6 +129,32 @@ func Load() (*Config, error) {
 		Firebase: FirebaseConfig{
 			CredentialsFile: getEnv("FIREBASE_CREDENTIALS_FILE", "../serviceAccountKey.json"),
 		},
+		Search: SearchConfig{
+			MinSimilarity: getEnvAsFloat("SEARCH_MIN_SIMILARITY", 0.3),
+		},
+		Inventory: InventoryConfig{
+			ReservationTTL: getEnvAsDuration("RESERVATION_TTL", 15*time.Minute),
+			SweepInterval:  getEnvAsDuration("RESERVATION_SWEEP_INTERVAL", time.Minute),
+		},
+		Pricing: PricingConfig{
+			ScheduleInterval: getEnvAsDuration("PRICE_SCHEDULE_INTERVAL", time.Minute),
+		},
+		Storage: StorageConfig{
+			Dir:             getEnv("STORAGE_DIR", "uploads"),
+			MaxImageSize:    int64(getEnvAsInt("IMAGE_MAX_SIZE", 5<<20)),
+			ProcessInterval: getEnvAsDuration("IMAGE_PROCESS_INTERVAL", 10*time.Second),
+		},
+		Locale: loadLocaleConfig(),
+		Related: RelatedConfig{
+			RefreshInterval: getEnvAsDuration("RELATED_REFRESH_INTERVAL", time.Hour),
+		},
+		Ratings: RatingsConfig{
+			PriorMean:   getEnvAsFloat("RATING_PRIOR_MEAN", 3),
+			PriorWeight: getEnvAsFloat("RATING_PRIOR_WEIGHT", 5),
+		},
+		Reviews: ReviewsConfig{
+			AutoApprove: getEnvAsBool("REVIEWS_AUTO_APPROVE", false),
+		},
 	}
 
 	// If in production, load DB config from AWS Secrets Manager
@@ -108,6 +199,17 @@ func loadDatabaseConfigFromEnv(env string) DatabaseConfig {
 	}
 }
 
+func loadLocaleConfig() LocaleConfig {
+	defaultLocale := getEnv("DEFAULT_LOCALE", "en")
+	supported := []string{defaultLocale}
+	for _, locale := range getEnvAsList("SUPPORTED_LOCALES") {
+		if !slices.Contains(supported, locale) {
+			supported = append(supported, locale)
+		}
+	}
+	return LocaleConfig{Default: defaultLocale, Supported: supported}
+}
+
 func (c *DatabaseConfig) GetDatabaseURL() string {
 	password := url.QueryEscape(c.Password)
 	url := fmt.Sprintf(
@@ -170,3 +272,53 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return value
 }
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := time.ParseDuration(valueStr)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsList splits a comma separated variable, dropping empty items
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
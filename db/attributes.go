@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MaxFacetValues caps how many values are returned per attribute facet, the
+// most common values are kept
+const MaxFacetValues = 50
+
+// Attributes are free-form product properties such as brand or material.
+// Values are plain strings so that they can be filtered on and counted.
+type Attributes map[string]string
+
+// Value stores a nil map as an empty object, the attributes column is never NULL
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]string(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Facet lists the values an attribute takes among the filtered products
+type Facet struct {
+	Attribute string       `json:"attribute"`
+	Values    []FacetValue `json:"values"`
+}
+
+// FacetValue is one value of a facet along with how many products have it
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// GetProductFacets counts the attribute values of the products matching the
+// filter, ordered by attribute and then by count. The filter on an
+// attribute is left out when counting that same attribute, so a facet keeps
+// showing the values that would widen the current selection.
+func (db *DB) GetProductFacets(ctx context.Context, filter ProductFilter) ([]Facet, error) {
+	query, args := buildFacetsQuery(filter, MaxFacetValues)
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query facets: %w", err)
+	}
+	defer rows.Close()
+
+	facets := []Facet{}
+	for rows.Next() {
+		var attribute string
+		var value FacetValue
+		if err := rows.Scan(&attribute, &value.Value, &value.Count); err != nil {
+			return nil, fmt.Errorf("failed to serialize facet: %w", err)
+		}
+		if len(facets) == 0 || facets[len(facets)-1].Attribute != attribute {
+			facets = append(facets, Facet{Attribute: attribute})
+		}
+		last := &facets[len(facets)-1]
+		last.Values = append(last.Values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query facets: %w", err)
+	}
+	return facets, nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// buildFacetsQuery counts attribute values over the products matching the
+// filter, keeping the maxValues most common values of each attribute
+func buildFacetsQuery(f ProductFilter, maxValues int) (string, []any) {
+	var q queryBuilder
+	q.whereFilter(f, "a.key")
+	sql := `SELECT key, value, count FROM (
+		SELECT a.key, a.value, count(*) AS count,
+			row_number() OVER (PARTITION BY a.key ORDER BY count(*) DESC, a.value) AS rank
+		FROM products, jsonb_each_text(attributes) AS a` + q.whereClause() + `
+		GROUP BY a.key, a.value
+	) f
+	WHERE rank <= ` + q.arg(maxValues) + `
+	ORDER BY key, rank`
+	return sql, q.args
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductAttributes(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Attributes: Attributes{"brand": "Acme", "material": "cotton"}},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Attributes: Attributes{"brand": "Globex", "material": "cotton"}},
+		{ID: 3, Name: "Jacket", Price: money.MustParse("99.99", "USD"), Image: "https://via.placeholder.com/150", Attributes: Attributes{"brand": "Acme", "material": "leather"}},
+		{ID: 4, Name: "Cap", Price: money.MustParse("9.99", "USD"), Image: "https://via.placeholder.com/150"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	product, err := db.GetProduct(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, Attributes{}, product.Attributes)
+
+	products, _, err := db.GetProducts(ctx, ProductFilter{Attributes: map[string][]string{"brand": {"Acme"}, "material": {"cotton", "wool"}}}, Page{})
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	assert.Equal(t, int64(1), products[0].ID)
+
+	// The brand facet ignores the brand filter, the material facet does not
+	facets, err := db.GetProductFacets(ctx, ProductFilter{Attributes: map[string][]string{"brand": {"Acme"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []Facet{
+		{Attribute: "brand", Values: []FacetValue{{Value: "Acme", Count: 2}, {Value: "Globex", Count: 1}}},
+		{Attribute: "material", Values: []FacetValue{{Value: "cotton", Count: 1}, {Value: "leather", Count: 1}}},
+	}, facets)
+
+	// A patch without attributes keeps them, one with attributes replaces them
+	name := "Leather Jacket"
+	product, err = db.PatchProduct(ctx, 3, ProductPatch{Name: &name}, "admin1")
+	require.NoError(t, err)
+	assert.Equal(t, Attributes{"brand": "Acme", "material": "leather"}, product.Attributes)
+
+	attributes := Attributes{"brand": "Initech"}
+	product, err = db.PatchProduct(ctx, 3, ProductPatch{Attributes: &attributes}, "admin1")
+	require.NoError(t, err)
+	assert.Equal(t, attributes, product.Attributes)
+}
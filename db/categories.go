@@ -0,0 +1,212 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Category is a node of the product taxonomy, root categories have no parent
+type Category struct {
+	ID        int64      `db:"id" json:"id"`
+	ParentID  *int64     `db:"parent_id" json:"parent_id"`
+	Name      string     `db:"name" json:"name"`
+	Slug      string     `db:"slug" json:"slug"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	Children  []Category `db:"-" json:"children"`
+}
+
+// ClientCategory is the payload accepted when creating or replacing a category
+type ClientCategory struct {
+	ParentID *int64 `json:"parent_id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+}
+
+const categoryColumns = "id, parent_id, name, slug, created_at"
+
+// descendantCategoriesCTE selects the id of category $1 and of every
+// category below it, it must be prefixed with WITH RECURSIVE
+const descendantCategoriesCTE = `subtree AS (
+		SELECT id FROM categories WHERE id = %s
+		UNION
+		SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+	)`
+
+// GetCategoryTree returns every root category with its descendants nested under Children
+func (db *DB) GetCategoryTree(ctx context.Context) ([]Category, error) {
+	rows, err := db.pool.Query(ctx, "SELECT "+categoryColumns+" FROM categories ORDER BY name, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	children := make(map[int64][]Category)
+	var roots []Category
+	for rows.Next() {
+		category, err := pgx.RowToStructByName[Category](rows)
+		if err != nil {
+			return nil, err
+		}
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+
+	return attachChildren(roots, children), nil
+}
+
+// attachChildren recursively fills in Children from a parent id lookup
+func attachChildren(categories []Category, children map[int64][]Category) []Category {
+	for i := range categories {
+		categories[i].Children = attachChildren(children[categories[i].ID], children)
+		if categories[i].Children == nil {
+			categories[i].Children = []Category{}
+		}
+	}
+	return categories
+}
+
+func (db *DB) GetCategory(ctx context.Context, id int64) (Category, error) {
+	rows, err := db.pool.Query(ctx, "SELECT "+categoryColumns+" FROM categories WHERE id = $1", id)
+	if err != nil {
+		return Category{}, fmt.Errorf("failed to query category: %w", err)
+	}
+	category, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Category])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Category{}, fmt.Errorf("category with id %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return Category{}, fmt.Errorf("failed to query category: %w", err)
+	}
+	return category, nil
+}
+
+func (db *DB) CreateCategory(ctx context.Context, c ClientCategory) (Category, error) {
+	rows, err := db.pool.Query(ctx,
+		`INSERT INTO categories (parent_id, name, slug)
+		VALUES ($1, $2, $3)
+		RETURNING `+categoryColumns,
+		c.ParentID, c.Name, c.Slug)
+	if err != nil {
+		return Category{}, fmt.Errorf("failed to insert category: %w", err)
+	}
+	category, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Category])
+	if isForeignKeyViolation(err) {
+		return Category{}, fmt.Errorf("parent category with id %d %w", *c.ParentID, ErrNotFound)
+	}
+	if err != nil {
+		return Category{}, fmt.Errorf("failed to insert category: %w", translateError(err))
+	}
+	return category, nil
+}
+
+// UpdateCategory replaces a category, moving it under a new parent is
+// rejected with ErrConflict when the parent is the category itself or one
+// of its descendants
+func (db *DB) UpdateCategory(ctx context.Context, id int64, c ClientCategory) (Category, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return Category{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if c.ParentID != nil {
+		// Lock the tree so a concurrent move cannot introduce a cycle
+		if _, err := tx.Exec(ctx, "LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return Category{}, fmt.Errorf("failed to lock categories: %w", err)
+		}
+		var cycle bool
+		err := tx.QueryRow(ctx, "WITH RECURSIVE "+fmt.Sprintf(descendantCategoriesCTE, "$1")+`
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`, id, *c.ParentID).Scan(&cycle)
+		if err != nil {
+			return Category{}, fmt.Errorf("failed to query category tree: %w", err)
+		}
+		if cycle {
+			return Category{}, fmt.Errorf("%w: category %d cannot be moved under itself or its descendant %d", ErrConflict, id, *c.ParentID)
+		}
+	}
+
+	rows, err := tx.Query(ctx,
+		`UPDATE categories SET parent_id = $2, name = $3, slug = $4
+		WHERE id = $1
+		RETURNING `+categoryColumns,
+		id, c.ParentID, c.Name, c.Slug)
+	if err != nil {
+		return Category{}, fmt.Errorf("failed to update category: %w", err)
+	}
+	category, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Category])
+	if isForeignKeyViolation(err) {
+		return Category{}, fmt.Errorf("parent category with id %d %w", *c.ParentID, ErrNotFound)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Category{}, fmt.Errorf("category with id %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return Category{}, fmt.Errorf("failed to update category: %w", translateError(err))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Category{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return category, nil
+}
+
+// DeleteCategory removes a category and its product links, categories that
+// still have children cannot be deleted
+func (db *DB) DeleteCategory(ctx context.Context, id int64) error {
+	tag, err := db.pool.Exec(ctx, "DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %w", translateError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("category with id %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// SetProductCategories replaces the set of categories a product belongs to
+func (db *DB) SetProductCategories(ctx context.Context, productID int64, categoryIDs []int64) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to query product: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM product_categories WHERE product_id = $1", productID); err != nil {
+		return fmt.Errorf("failed to clear product categories: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+	INSERT INTO product_categories (product_id, category_id)
+	SELECT $1, category_id FROM unnest($2::integer[]) AS category_id
+	ON CONFLICT DO NOTHING
+	`, productID, categoryIDs)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("category %w", ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link product categories: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
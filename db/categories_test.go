@@ -0,0 +1,170 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestGetCategoryTree(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testCategories := []Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+		{ID: 2, ParentID: ptr(int64(1)), Name: "Desks", Slug: "desks"},
+		{ID: 3, ParentID: ptr(int64(1)), Name: "Chairs", Slug: "chairs"},
+		{ID: 4, ParentID: ptr(int64(2)), Name: "Standing Desks", Slug: "standing-desks"},
+		{ID: 5, Name: "Lighting", Slug: "lighting"},
+	}
+	err := PopulateTestData(ctx, db, "categories", testCategories)
+	require.NoError(t, err)
+
+	tree, err := db.GetCategoryTree(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, tree, 2)
+	assert.Equal(t, "Furniture", tree[0].Name)
+	assert.Equal(t, "Lighting", tree[1].Name)
+	assert.Empty(t, tree[1].Children)
+
+	furniture := tree[0]
+	require.Len(t, furniture.Children, 2)
+	assert.Equal(t, "Chairs", furniture.Children[0].Name)
+	assert.Equal(t, "Desks", furniture.Children[1].Name)
+	require.Len(t, furniture.Children[1].Children, 1)
+	assert.Equal(t, "Standing Desks", furniture.Children[1].Children[0].Name)
+}
+
+func TestCreateCategory(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	root, err := db.CreateCategory(ctx, ClientCategory{Name: "Furniture", Slug: "furniture"})
+	require.NoError(t, err)
+	assert.Nil(t, root.ParentID)
+
+	child, err := db.CreateCategory(ctx, ClientCategory{ParentID: &root.ID, Name: "Desks", Slug: "desks"})
+	require.NoError(t, err)
+	require.NotNil(t, child.ParentID)
+	assert.Equal(t, root.ID, *child.ParentID)
+
+	_, err = db.CreateCategory(ctx, ClientCategory{Name: "Duplicate", Slug: "desks"})
+	require.ErrorIs(t, err, ErrConflict)
+
+	_, err = db.CreateCategory(ctx, ClientCategory{ParentID: ptr(int64(42)), Name: "Orphan", Slug: "orphan"})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUpdateCategory(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testCategories := []Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+		{ID: 2, ParentID: ptr(int64(1)), Name: "Desks", Slug: "desks"},
+		{ID: 3, ParentID: ptr(int64(2)), Name: "Standing Desks", Slug: "standing-desks"},
+		{ID: 4, Name: "Office", Slug: "office"},
+	}
+	err := PopulateTestData(ctx, db, "categories", testCategories)
+	require.NoError(t, err)
+
+	moved, err := db.UpdateCategory(ctx, 2, ClientCategory{ParentID: ptr(int64(4)), Name: "Office Desks", Slug: "office-desks"})
+	require.NoError(t, err)
+	assert.Equal(t, "Office Desks", moved.Name)
+	require.NotNil(t, moved.ParentID)
+	assert.Equal(t, int64(4), *moved.ParentID)
+
+	// Moving a category below its own descendant would create a cycle
+	_, err = db.UpdateCategory(ctx, 2, ClientCategory{ParentID: ptr(int64(3)), Name: "Desks", Slug: "desks"})
+	require.ErrorIs(t, err, ErrConflict)
+
+	_, err = db.UpdateCategory(ctx, 2, ClientCategory{ParentID: ptr(int64(2)), Name: "Desks", Slug: "desks"})
+	require.ErrorIs(t, err, ErrConflict)
+
+	_, err = db.UpdateCategory(ctx, 42, ClientCategory{Name: "Missing", Slug: "missing"})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDeleteCategory(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testCategories := []Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+		{ID: 2, ParentID: ptr(int64(1)), Name: "Desks", Slug: "desks"},
+	}
+	err := PopulateTestData(ctx, db, "categories", testCategories)
+	require.NoError(t, err)
+
+	err = db.DeleteCategory(ctx, 1)
+	require.ErrorIs(t, err, ErrConflict)
+
+	err = db.DeleteCategory(ctx, 2)
+	require.NoError(t, err)
+	err = db.DeleteCategory(ctx, 1)
+	require.NoError(t, err)
+
+	_, err = db.GetCategory(ctx, 1)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGetProductsByCategory(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Standing Desk", Price: money.MustParse("399.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testCategories := []Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+		{ID: 2, ParentID: ptr(int64(1)), Name: "Desks", Slug: "desks"},
+		{ID: 3, ParentID: ptr(int64(2)), Name: "Standing Desks", Slug: "standing-desks"},
+		{ID: 4, Name: "Lighting", Slug: "lighting"},
+	}
+	err = PopulateTestData(ctx, db, "categories", testCategories)
+	require.NoError(t, err)
+
+	require.NoError(t, db.SetProductCategories(ctx, 1, []int64{2}))
+	require.NoError(t, db.SetProductCategories(ctx, 2, []int64{3}))
+	require.NoError(t, db.SetProductCategories(ctx, 3, []int64{4, 2}))
+	// Replacing the links drops the previous ones
+	require.NoError(t, db.SetProductCategories(ctx, 3, []int64{4}))
+
+	tests := []struct {
+		categoryID int64
+		expected   []int64
+	}{
+		{categoryID: 1, expected: []int64{1, 2}},
+		{categoryID: 2, expected: []int64{1, 2}},
+		{categoryID: 3, expected: []int64{2}},
+		{categoryID: 4, expected: []int64{3}},
+	}
+
+	for _, tt := range tests {
+		products, _, err := db.GetProducts(ctx, ProductFilter{CategoryID: &tt.categoryID}, Page{})
+		require.NoError(t, err)
+		var ids []int64
+		for _, p := range products {
+			ids = append(ids, p.ID)
+		}
+		assert.Equal(t, tt.expected, ids, "category %d", tt.categoryID)
+	}
+
+	err = db.SetProductCategories(ctx, 42, []int64{1})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = db.SetProductCategories(ctx, 1, []int64{42})
+	require.ErrorIs(t, err, ErrNotFound)
+}
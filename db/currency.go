@@ -0,0 +1,252 @@
+package db
+
+import (
+	"catalogapi/money"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ExchangeRate converts base currency prices into Currency. Rate is the
+// number of units of Currency per unit of money.DefaultCurrency, kept as a
+// decimal string so it is never rounded through a float.
+type ExchangeRate struct {
+	Currency          string             `db:"currency" json:"currency"`
+	Rate              string             `db:"rate" json:"rate"`
+	RoundingMode      money.RoundingMode `db:"rounding_mode" json:"rounding_mode"`
+	RoundingIncrement int64              `db:"rounding_increment" json:"rounding_increment"`
+	UpdatedAt         time.Time          `db:"updated_at" json:"updated_at"`
+}
+
+// ClientExchangeRate is the payload accepted when setting an exchange rate
+type ClientExchangeRate struct {
+	Rate              string             `json:"rate"`
+	RoundingMode      money.RoundingMode `json:"rounding_mode"`
+	RoundingIncrement int64              `json:"rounding_increment"`
+}
+
+// rate is cast to text so its exact decimal representation is kept
+const exchangeRateColumns = "currency, rate::text AS rate, rounding_mode, rounding_increment, updated_at"
+
+// Rounding returns the rounding rule applied to prices converted with r
+func (r ExchangeRate) Rounding() money.Rounding {
+	return money.Rounding{Mode: r.RoundingMode, Increment: r.RoundingIncrement}
+}
+
+// GetExchangeRates returns every configured exchange rate ordered by currency
+func (db *DB) GetExchangeRates(ctx context.Context) ([]ExchangeRate, error) {
+	rows, err := db.pool.Query(ctx, "SELECT "+exchangeRateColumns+" FROM exchange_rates ORDER BY currency")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	rates, err := pgx.CollectRows(rows, pgx.RowToStructByName[ExchangeRate])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+func (db *DB) GetExchangeRate(ctx context.Context, currency string) (ExchangeRate, error) {
+	rows, err := db.pool.Query(ctx, "SELECT "+exchangeRateColumns+" FROM exchange_rates WHERE currency = $1", currency)
+	if err != nil {
+		return ExchangeRate{}, fmt.Errorf("failed to query exchange rate: %w", err)
+	}
+	rate, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ExchangeRate])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ExchangeRate{}, fmt.Errorf("exchange rate for %s %w", currency, ErrNotFound)
+	}
+	if err != nil {
+		return ExchangeRate{}, fmt.Errorf("failed to query exchange rate: %w", err)
+	}
+	return rate, nil
+}
+
+// SetExchangeRate creates or replaces the exchange rate of a currency
+func (db *DB) SetExchangeRate(ctx context.Context, currency string, r ClientExchangeRate) (ExchangeRate, error) {
+	rows, err := db.pool.Query(ctx,
+		`INSERT INTO exchange_rates (currency, rate, rounding_mode, rounding_increment)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (currency) DO UPDATE SET
+			rate = EXCLUDED.rate,
+			rounding_mode = EXCLUDED.rounding_mode,
+			rounding_increment = EXCLUDED.rounding_increment,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING `+exchangeRateColumns,
+		currency, r.Rate, r.RoundingMode, r.RoundingIncrement)
+	if err != nil {
+		return ExchangeRate{}, fmt.Errorf("failed to set exchange rate: %w", err)
+	}
+	rate, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ExchangeRate])
+	if err != nil {
+		return ExchangeRate{}, fmt.Errorf("failed to set exchange rate: %w", translateError(err))
+	}
+	return rate, nil
+}
+
+func (db *DB) DeleteExchangeRate(ctx context.Context, currency string) error {
+	tag, err := db.pool.Exec(ctx, "DELETE FROM exchange_rates WHERE currency = $1", currency)
+	if err != nil {
+		return fmt.Errorf("failed to delete exchange rate: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("exchange rate for %s %w", currency, ErrNotFound)
+	}
+	return nil
+}
+
+// SetProductPrice stores an explicit price of a product in a foreign
+// currency, it is used instead of converting the base price
+func (db *DB) SetProductPrice(ctx context.Context, productID int64, price money.Money) error {
+	_, err := db.pool.Exec(ctx,
+		`INSERT INTO product_prices (product_id, currency, price)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, currency) DO UPDATE SET price = EXCLUDED.price`,
+		productID, price.Currency(), price)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set product price: %w", err)
+	}
+	return nil
+}
+
+// GetProductPrices returns the explicit foreign currency prices of a product
+func (db *DB) GetProductPrices(ctx context.Context, productID int64) ([]money.Money, error) {
+	rows, err := db.pool.Query(ctx,
+		"SELECT currency, price FROM product_prices WHERE product_id = $1 ORDER BY currency",
+		productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := []money.Money{}
+	for rows.Next() {
+		var currency string
+		var amount pgtype.Numeric
+		if err := rows.Scan(&currency, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan product price: %w", err)
+		}
+		price, err := priceIn(currency, amount)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query product prices: %w", err)
+	}
+	return prices, nil
+}
+
+func (db *DB) DeleteProductPrice(ctx context.Context, productID int64, currency string) error {
+	tag, err := db.pool.Exec(ctx,
+		"DELETE FROM product_prices WHERE product_id = $1 AND currency = $2",
+		productID, currency)
+	if err != nil {
+		return fmt.Errorf("failed to delete product price: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s price of product %d %w", currency, productID, ErrNotFound)
+	}
+	return nil
+}
+
+// ConvertPrices rewrites the prices of products and their variants in
+// currency. Explicit product prices win, everything else is converted from
+// the base price with the currency's exchange rate and rounding rule.
+func (db *DB) ConvertPrices(ctx context.Context, currency string, products []*Product) error {
+	if currency == money.DefaultCurrency || len(products) == 0 {
+		return nil
+	}
+
+	exchangeRate, err := db.GetExchangeRate(ctx, currency)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: no exchange rate for %s", ErrUnsupportedCurrency, currency)
+	}
+	if err != nil {
+		return err
+	}
+	rate, err := money.ParseRate(exchangeRate.Rate)
+	if err != nil {
+		return fmt.Errorf("invalid exchange rate for %s: %w", currency, err)
+	}
+	rounding := exchangeRate.Rounding()
+
+	ids := make([]int64, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	overrides, err := db.productPricesIn(ctx, currency, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		if price, ok := overrides[p.ID]; ok {
+			p.Price = price
+		} else if p.Price, err = p.Price.Convert(rate, currency, rounding); err != nil {
+			return fmt.Errorf("failed to convert price of product %d: %w", p.ID, err)
+		}
+
+		for i := range p.Variants {
+			v := &p.Variants[i]
+			if v.Price == nil {
+				continue
+			}
+			converted, err := v.Price.Convert(rate, currency, rounding)
+			if err != nil {
+				return fmt.Errorf("failed to convert price of variant %d: %w", v.ID, err)
+			}
+			v.Price = &converted
+		}
+	}
+	return nil
+}
+
+// productPricesIn returns the explicit prices in currency of the given products
+func (db *DB) productPricesIn(ctx context.Context, currency string, productIDs []int64) (map[int64]money.Money, error) {
+	rows, err := db.pool.Query(ctx,
+		"SELECT product_id, price FROM product_prices WHERE currency = $1 AND product_id = ANY($2)",
+		currency, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := make(map[int64]money.Money)
+	for rows.Next() {
+		var productID int64
+		var amount pgtype.Numeric
+		if err := rows.Scan(&productID, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan product price: %w", err)
+		}
+		price, err := priceIn(currency, amount)
+		if err != nil {
+			return nil, err
+		}
+		prices[productID] = price
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query product prices: %w", err)
+	}
+	return prices, nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// priceIn reads a numeric column as an amount of currency
+func priceIn(currency string, amount pgtype.Numeric) (money.Money, error) {
+	price := money.New(0, currency)
+	if err := price.ScanNumeric(amount); err != nil {
+		return money.Money{}, fmt.Errorf("failed to read %s price: %w", currency, err)
+	}
+	return price, nil
+}
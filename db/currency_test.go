@@ -0,0 +1,112 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetExchangeRate(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	rate, err := db.SetExchangeRate(ctx, "EUR", ClientExchangeRate{Rate: "0.92", RoundingMode: money.RoundHalfUp, RoundingIncrement: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", rate.Currency)
+	assert.Equal(t, "0.92000000", rate.Rate)
+
+	// Setting the rate again replaces it
+	rate, err = db.SetExchangeRate(ctx, "EUR", ClientExchangeRate{Rate: "0.9", RoundingMode: money.RoundDown, RoundingIncrement: 5})
+	require.NoError(t, err)
+	assert.Equal(t, money.Rounding{Mode: money.RoundDown, Increment: 5}, rate.Rounding())
+
+	_, err = db.SetExchangeRate(ctx, "ILS", ClientExchangeRate{Rate: "3.65", RoundingMode: money.RoundHalfUp, RoundingIncrement: 10})
+	require.NoError(t, err)
+
+	rates, err := db.GetExchangeRates(ctx)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	assert.Equal(t, "EUR", rates[0].Currency)
+	assert.Equal(t, "ILS", rates[1].Currency)
+
+	err = db.DeleteExchangeRate(ctx, "EUR")
+	require.NoError(t, err)
+
+	_, err = db.GetExchangeRate(ctx, "EUR")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = db.DeleteExchangeRate(ctx, "EUR")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestProductPrices(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	err = db.SetProductPrice(ctx, 1, money.MustParse("17.50", "EUR"))
+	require.NoError(t, err)
+	err = db.SetProductPrice(ctx, 1, money.MustParse("18", "EUR"))
+	require.NoError(t, err)
+	err = db.SetProductPrice(ctx, 1, money.MustParse("2500", "JPY"))
+	require.NoError(t, err)
+
+	prices, err := db.GetProductPrices(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []money.Money{money.MustParse("18.00", "EUR"), money.MustParse("2500", "JPY")}, prices)
+
+	err = db.SetProductPrice(ctx, 42, money.MustParse("1", "EUR"))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = db.DeleteProductPrice(ctx, 1, "JPY")
+	require.NoError(t, err)
+	err = db.DeleteProductPrice(ctx, 1, "JPY")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestConvertPrices(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	_, err = db.SetExchangeRate(ctx, "ILS", ClientExchangeRate{Rate: "3.61", RoundingMode: money.RoundHalfUp, RoundingIncrement: 10})
+	require.NoError(t, err)
+	err = db.SetProductPrice(ctx, 2, money.MustParse("179.90", "ILS"))
+	require.NoError(t, err)
+
+	variantPrice := money.MustParse("24.99", "USD")
+	products := []Product{testProducts[0], testProducts[1]}
+	products[0].Variants = []Variant{{ID: 1, ProductID: 1, SKU: "TS-XL", Price: &variantPrice}, {ID: 2, ProductID: 1, SKU: "TS-S"}}
+
+	err = db.ConvertPrices(ctx, "ILS", []*Product{&products[0], &products[1]})
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("72.20", "ILS"), products[0].Price)
+	assert.Equal(t, money.MustParse("179.90", "ILS"), products[1].Price)
+	require.NotNil(t, products[0].Variants[0].Price)
+	assert.Equal(t, money.MustParse("90.20", "ILS"), *products[0].Variants[0].Price)
+	assert.Nil(t, products[0].Variants[1].Price)
+	// The variant price is copied, not converted in place
+	assert.Equal(t, money.MustParse("24.99", "USD"), variantPrice)
+
+	// The base currency is left untouched
+	product := testProducts[0]
+	err = db.ConvertPrices(ctx, money.DefaultCurrency, []*Product{&product})
+	require.NoError(t, err)
+	assert.Equal(t, testProducts[0].Price, product.Price)
+
+	err = db.ConvertPrices(ctx, "GBP", []*Product{&product})
+	require.ErrorIs(t, err, ErrUnsupportedCurrency)
+}
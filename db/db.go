@@ -1,7 +1,9 @@
 package db
 
 import (
+	"catalogapi/money"
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,18 +13,63 @@ import (
 
 // DB represents the database connection pool
 type DB struct {
-	pool *pgxpool.Pool
+	pool            *pgxpool.Pool
+	ratingSummaries ratingSummaryCache
 }
 
+// productColumns lists the products columns that map onto Product, use it
+// instead of * so that columns like search_vector are not selected
+const productColumns = "id, name, price, image, description, attributes, average_rating, review_count, external_id, archived_at, created_at"
+
 type Product struct {
-	ID          int64     `db:"id" json:"id"`
-	Name        string    `db:"name" json:"name"`
-	Price       float64   `db:"price" json:"price"`
-	Image       string    `db:"image" json:"image"`
-	Description string    `db:"description" json:"description"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	ID          int64       `db:"id" json:"id"`
+	Name        string      `db:"name" json:"name"`
+	Price       money.Money `db:"price" json:"price"`
+	Image       string      `db:"image" json:"image"`
+	Description string      `db:"description" json:"description"`
+	Attributes  Attributes  `db:"attributes" json:"attributes"`
+	// AverageRating and ReviewCount are kept up to date by a trigger on
+	// reviews, AverageRating is 0 while there are no reviews
+	AverageRating float64 `db:"average_rating" json:"average_rating"`
+	ReviewCount   int64   `db:"review_count" json:"review_count"`
+	// ExternalID identifies the product in the system it was imported from
+	ExternalID *string `db:"external_id" json:"external_id"`
+	// ArchivedAt is set once the product is deleted, archived products are
+	// left out of listings but can still be read by id and restored
+	ArchivedAt *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	Variants   []Variant  `db:"-" json:"variants,omitempty"`
+	// Images are the uploaded images of the product, Image stays the primary one
+	Images []ProductImage `db:"-" json:"images,omitempty"`
+	// Locale is the locale Name and Description are in, set by Localize
+	Locale string `db:"-" json:"locale,omitempty"`
+	// QuantityAvailable is the unreserved stock of the product and its variants
+	QuantityAvailable int64 `db:"-" json:"quantity_available"`
+	InStock           bool  `db:"-" json:"in_stock"`
+}
+
+// ClientProduct is the payload accepted when creating or replacing a product
+type ClientProduct struct {
+	Name        string      `json:"name"`
+	Price       money.Money `json:"price"`
+	Image       string      `json:"image"`
+	Description string      `json:"description"`
+	Attributes  Attributes  `json:"attributes"`
+}
+
+// ProductPatch holds a partial product update, nil fields are left unchanged
+type ProductPatch struct {
+	Name        *string      `json:"name"`
+	Price       *money.Money `json:"price"`
+	Image       *string      `json:"image"`
+	Description *string      `json:"description"`
+	// Attributes replaces all of the attributes when set
+	Attributes *Attributes `json:"attributes"`
 }
 
+// reviewColumns lists the reviews columns that map onto Review
+const reviewColumns = "id, user_id, product_id, variant_id, review_title, review_content, stars, created_at, updated_at"
+
 type Review struct {
 	ID            int64     `db:"id"`
 	UserId        string    `db:"user_id"`
@@ -31,22 +78,39 @@ type Review struct {
 	ReviewContent string    `db:"review_content"`
 	Stars         float64   `db:"stars"`
 	CreatedAt     time.Time `db:"created_at"`
+	VariantID     *int64    `db:"variant_id"`
+	UpdatedAt     time.Time `db:"updated_at"`
 }
 
 type ClientReview struct {
 	ProductID     int64   `json:"productId"`
+	VariantID     *int64  `json:"variantId,omitempty"`
 	ReviewTitle   string  `json:"reviewTitle"`
 	ReviewContent string  `json:"reviewContent"`
 	Stars         float64 `json:"stars"`
 }
-type SafeReview struct {
-	ID            int64   `json:"id"`
-	ProductID     int64   `json:"productId"`
+
+// ReviewEdit is the payload accepted when an author rewrites their review,
+// the reviewed product cannot be changed
+type ReviewEdit struct {
+	VariantID     *int64  `json:"variantId,omitempty"`
 	ReviewTitle   string  `json:"reviewTitle"`
 	ReviewContent string  `json:"reviewContent"`
 	Stars         float64 `json:"stars"`
 }
 
+type SafeReview struct {
+	ID            int64     `json:"id"`
+	ProductID     int64     `json:"productId"`
+	VariantID     *int64    `json:"variantId,omitempty"`
+	ReviewTitle   string    `json:"reviewTitle"`
+	ReviewContent string    `json:"reviewContent"`
+	Stars         float64   `json:"stars"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	// Status is only shown to the author, in the response to their write
+	Status string `json:"status,omitempty"`
+}
+
 // New creates a new database connection pool
 func New(databaseURL string) (*DB, error) {
 	fmt.Println("Parsing database configuration...")
@@ -92,10 +156,23 @@ func (db *DB) HealthCheck(ctx context.Context) error {
 	return db.pool.Ping(ctx)
 }
 
-func (db *DB) GetProducts(ctx context.Context) ([]Product, error) {
-	rows, err := db.pool.Query(ctx, "SELECT * FROM products ORDER BY id")
+// GetProducts returns one page of products matching the filter, along with
+// the cursor of the next page or an empty string when this is the last one
+func (db *DB) GetProducts(ctx context.Context, filter ProductFilter, page Page) ([]Product, string, error) {
+	c, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit := page.limit()
+
+	query, args, err := buildProductsQuery(filter, c, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := db.pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query products: %w", err)
+		return nil, "", fmt.Errorf("failed to query products: %w", err)
 	}
 	defer rows.Close()
 
@@ -103,53 +180,331 @@ func (db *DB) GetProducts(ctx context.Context) ([]Product, error) {
 	for rows.Next() {
 		product, err := pgx.RowToStructByName[Product](rows)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		products = append(products, product)
 	}
-	return products, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to query products: %w", err)
+	}
+
+	var next string
+	if len(products) > limit {
+		products = products[:limit]
+		keys, _ := filter.orderKeys()
+		next = encodeCursor(productCursor(products[limit-1], keys))
+	}
+
+	refs := make([]*Product, len(products))
+	for i := range products {
+		refs[i] = &products[i]
+	}
+	if err := db.attachAvailability(ctx, refs); err != nil {
+		return nil, "", err
+	}
+	return products, next, nil
 }
 
 func (db *DB) GetProduct(ctx context.Context, id int64) (Product, error) {
-	rows, err := db.pool.Query(ctx, "SELECT * FROM products WHERE id = $1", id)
+	rows, err := db.pool.Query(ctx, "SELECT "+productColumns+" FROM products WHERE id = $1", id)
 	if err != nil {
 		return Product{}, fmt.Errorf("failed to query product: %w", err)
 	}
 	defer rows.Close()
 
 	if !rows.Next() {
-		return Product{}, fmt.Errorf("product with id %d not found", id)
+		if err := rows.Err(); err != nil {
+			return Product{}, fmt.Errorf("failed to query product: %w", err)
+		}
+		return Product{}, fmt.Errorf("product with id %d %w", id, ErrNotFound)
 	}
 
 	product, err := pgx.RowToStructByName[Product](rows)
 	if err != nil {
 		return Product{}, fmt.Errorf("failed to serialize product: %w", err)
 	}
+	rows.Close()
+
+	if err := db.attachAvailability(ctx, []*Product{&product}); err != nil {
+		return Product{}, err
+	}
+	return product, nil
+}
+
+func (db *DB) CreateProduct(ctx context.Context, p ClientProduct) (Product, error) {
+	rows, err := db.pool.Query(ctx,
+		`INSERT INTO products (name, price, image, description, attributes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+productColumns,
+		p.Name, p.Price, p.Image, p.Description, p.Attributes)
+	if err != nil {
+		return Product{}, fmt.Errorf("failed to insert product: %w", translateError(err))
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Product{}, fmt.Errorf("failed to insert product: %w", translateError(err))
+		}
+		return Product{}, fmt.Errorf("failed to insert product: no row returned")
+	}
+
+	product, err := pgx.RowToStructByName[Product](rows)
+	if err != nil {
+		return Product{}, fmt.Errorf("failed to serialize product: %w", err)
+	}
+	return product, nil
+}
+
+// UpdateProduct replaces a product, a price change is recorded in the price
+// history as made by userID
+func (db *DB) UpdateProduct(ctx context.Context, id int64, p ClientProduct, userID string) (Product, error) {
+	return db.updateProduct(ctx, id, userID, "update",
+		`UPDATE products SET name = $2, price = $3, image = $4, description = $5, attributes = $6
+		WHERE id = $1
+		RETURNING `+productColumns,
+		id, p.Name, p.Price, p.Image, p.Description, p.Attributes)
+}
+
+// PatchProduct updates the non-nil fields of a product, a price change is
+// recorded in the price history as made by userID
+func (db *DB) PatchProduct(ctx context.Context, id int64, p ProductPatch, userID string) (Product, error) {
+	return db.updateProduct(ctx, id, userID, "patch",
+		`UPDATE products SET
+			name = COALESCE($2, name),
+			price = COALESCE($3, price),
+			image = COALESCE($4, image),
+			description = COALESCE($5, description),
+			attributes = COALESCE($6, attributes)
+		WHERE id = $1
+		RETURNING `+productColumns,
+		id, p.Name, p.Price, p.Image, p.Description, p.Attributes)
+}
+
+// updateProduct runs an UPDATE ... RETURNING on the product with the given
+// id and records its price change in the same transaction. The row is locked
+// first so the recorded old price is the one actually replaced.
+func (db *DB) updateProduct(ctx context.Context, id int64, userID, action, query string, args ...any) (Product, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return Product{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var oldPrice money.Money
+	err = tx.QueryRow(ctx, "SELECT price FROM products WHERE id = $1 FOR UPDATE", id).Scan(&oldPrice)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Product{}, fmt.Errorf("product with id %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return Product{}, fmt.Errorf("failed to %s product: %w", action, err)
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return Product{}, fmt.Errorf("failed to %s product: %w", action, translateError(err))
+	}
+	product, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Product])
+	if err != nil {
+		return Product{}, fmt.Errorf("failed to %s product: %w", action, translateError(err))
+	}
+
+	if err := recordPriceChange(ctx, tx, id, oldPrice, product.Price, userID, nil); err != nil {
+		return Product{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Product{}, fmt.Errorf("failed to %s product: %w", action, err)
+	}
+	return product, nil
+}
+
+// ArchiveProduct soft deletes a product. Its reviews, images and price
+// history are kept so that RestoreProduct can bring it back as it was.
+// Archiving an archived product keeps its original archive time.
+func (db *DB) ArchiveProduct(ctx context.Context, id int64) (Product, error) {
+	return db.setProductArchived(ctx, id, "archive",
+		"UPDATE products SET archived_at = COALESCE(archived_at, now()) WHERE id = $1 RETURNING "+productColumns)
+}
+
+// RestoreProduct brings an archived product back into listings
+func (db *DB) RestoreProduct(ctx context.Context, id int64) (Product, error) {
+	return db.setProductArchived(ctx, id, "restore",
+		"UPDATE products SET archived_at = NULL WHERE id = $1 RETURNING "+productColumns)
+}
+
+func (db *DB) setProductArchived(ctx context.Context, id int64, action, query string) (Product, error) {
+	rows, err := db.pool.Query(ctx, query, id)
+	if err != nil {
+		return Product{}, fmt.Errorf("failed to %s product: %w", action, err)
+	}
+	product, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Product])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Product{}, fmt.Errorf("product with id %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return Product{}, fmt.Errorf("failed to %s product: %w", action, err)
+	}
 	return product, nil
 }
 
-func (db *DB) PostReview(ctx context.Context, review ClientReview, userId string) (Review, error) {
+// PostReview writes the first review of userId for a product, in status.
+// A second review of the same product is rejected with a ReviewExistsError.
+func (db *DB) PostReview(ctx context.Context, review ClientReview, userId, status string) (Review, error) {
+	if err := checkReviewVariant(ctx, db.pool, review.ProductID, review.VariantID); err != nil {
+		return Review{}, err
+	}
+
 	var newReview Review
 	err := db.pool.QueryRow(ctx,
-		`INSERT INTO reviews (user_id, product_id, review_title, review_content, stars) 
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, user_id, product_id, review_title, review_content, stars, created_at`,
-		userId, review.ProductID, review.ReviewTitle, review.ReviewContent, review.Stars).Scan(
-		&newReview.ID, &newReview.UserId, &newReview.ProductID, &newReview.ReviewTitle,
-		&newReview.ReviewContent, &newReview.Stars, &newReview.CreatedAt)
+		`INSERT INTO reviews (user_id, product_id, variant_id, review_title, review_content, stars, status) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, product_id) DO NOTHING
+		RETURNING `+reviewColumns,
+		userId, review.ProductID, review.VariantID, review.ReviewTitle, review.ReviewContent, review.Stars, status).Scan(
+		&newReview.ID, &newReview.UserId, &newReview.ProductID, &newReview.VariantID, &newReview.ReviewTitle,
+		&newReview.ReviewContent, &newReview.Stars, &newReview.CreatedAt, &newReview.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return Review{}, fmt.Errorf("product with id %d %w", review.ProductID, ErrNotFound)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Review{}, db.reviewExists(ctx, review.ProductID, userId)
+	}
 	if err != nil {
 		return Review{}, fmt.Errorf("failed to insert review: %w", err)
 	}
+	db.ratingSummaries.invalidate(newReview.ProductID)
 	return newReview, nil
 }
 
-func (db *DB) GetProductReviews(ctx context.Context, productId int64) ([]Review, error) {
+// UpsertReview creates the review of userId for a product, or replaces it
+// if they already wrote one, either way the review ends up in status. It
+// reports whether the review was created.
+func (db *DB) UpsertReview(ctx context.Context, productId int64, userId string, edit ReviewEdit, status string) (Review, bool, error) {
+	if err := checkReviewVariant(ctx, db.pool, productId, edit.VariantID); err != nil {
+		return Review{}, false, err
+	}
+
+	var review Review
+	var created bool
+	// xmax is only set on the row when the conflict turned the insert into an update
+	err := db.pool.QueryRow(ctx, `
+	INSERT INTO reviews (user_id, product_id, variant_id, review_title, review_content, stars, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (user_id, product_id) DO UPDATE SET
+		variant_id = EXCLUDED.variant_id,
+		review_title = EXCLUDED.review_title,
+		review_content = EXCLUDED.review_content,
+		stars = EXCLUDED.stars,
+		status = EXCLUDED.status,
+		rejection_reason = NULL,
+		moderated_by = NULL,
+		moderated_at = NULL,
+		updated_at = now()
+	RETURNING `+reviewColumns+`, xmax = 0
+	`, userId, productId, edit.VariantID, edit.ReviewTitle, edit.ReviewContent, edit.Stars, status).Scan(
+		&review.ID, &review.UserId, &review.ProductID, &review.VariantID, &review.ReviewTitle,
+		&review.ReviewContent, &review.Stars, &review.CreatedAt, &review.UpdatedAt, &created)
+	if isForeignKeyViolation(err) {
+		return Review{}, false, fmt.Errorf("product with id %d %w", productId, ErrNotFound)
+	}
+	if err != nil {
+		return Review{}, false, fmt.Errorf("failed to upsert review: %w", err)
+	}
+	db.ratingSummaries.invalidate(productId)
+	return review, created, nil
+}
+
+// UpdateReview rewrites a review on behalf of its author and puts it in
+// status, other users get ErrForbidden
+func (db *DB) UpdateReview(ctx context.Context, id int64, userId string, edit ReviewEdit, status string) (Review, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return Review{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	productId, err := lockOwnReview(ctx, tx, id, userId)
+	if err != nil {
+		return Review{}, err
+	}
+	if err := checkReviewVariant(ctx, tx, productId, edit.VariantID); err != nil {
+		return Review{}, err
+	}
+
+	rows, err := tx.Query(ctx, `
+	UPDATE reviews SET variant_id = $2, review_title = $3, review_content = $4, stars = $5, status = $6,
+		rejection_reason = NULL, moderated_by = NULL, moderated_at = NULL, updated_at = now()
+	WHERE id = $1
+	RETURNING `+reviewColumns,
+		id, edit.VariantID, edit.ReviewTitle, edit.ReviewContent, edit.Stars, status)
+	if err != nil {
+		return Review{}, fmt.Errorf("failed to update review: %w", err)
+	}
+	review, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Review])
+	if err != nil {
+		return Review{}, fmt.Errorf("failed to update review: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Review{}, fmt.Errorf("failed to commit review: %w", err)
+	}
+	db.ratingSummaries.invalidate(productId)
+	return review, nil
+}
+
+// DeleteReview deletes a review on behalf of its author, other users get
+// ErrForbidden
+func (db *DB) DeleteReview(ctx context.Context, id int64, userId string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	productId, err := lockOwnReview(ctx, tx, id, userId)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM product_reviews WHERE review_id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete review: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM reviews WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete review: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit review: %w", err)
+	}
+	db.ratingSummaries.invalidate(productId)
+	return nil
+}
+
+// GetProductReviews returns one page of a product's approved reviews ordered
+// by id, along with the cursor of the next page
+func (db *DB) GetProductReviews(ctx context.Context, productId int64, page Page) ([]Review, string, error) {
+	c, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit := page.limit()
+
+	var exists bool
+	err = db.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", productId).Scan(&exists)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query product: %w", err)
+	}
+	if !exists {
+		return nil, "", fmt.Errorf("product with id %d %w", productId, ErrNotFound)
+	}
+
 	rows, err := db.pool.Query(ctx, `
-	SELECT * FROM reviews WHERE product_id = $1
-	`, productId)
+	SELECT `+reviewColumns+` FROM reviews
+	WHERE product_id = $1 AND status = $2 AND id > $3
+	ORDER BY id LIMIT $4
+	`, productId, ReviewApproved, c.ID, limit+1)
 
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	defer rows.Close()
@@ -157,10 +512,74 @@ func (db *DB) GetProductReviews(ctx context.Context, productId int64) ([]Review,
 	for rows.Next() {
 		review, err := pgx.RowToStructByName[Review](rows)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		reviews = append(reviews, review)
 	}
-	return reviews, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(reviews) > limit {
+		reviews = reviews[:limit]
+		next = encodeCursor(cursor{ID: reviews[limit-1].ID})
+	}
+	return reviews, next, nil
+}
 
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// rowQuerier runs single row queries, on the pool or within a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// checkReviewVariant makes sure the variant a review points at, if any,
+// belongs to the reviewed product
+func checkReviewVariant(ctx context.Context, q rowQuerier, productId int64, variantId *int64) error {
+	if variantId == nil {
+		return nil
+	}
+	var exists bool
+	err := q.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM product_variants WHERE id = $1 AND product_id = $2)",
+		*variantId, productId).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to query variant: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("variant with id %d of product %d %w", *variantId, productId, ErrNotFound)
+	}
+	return nil
+}
+
+// reviewExists builds the error returned when userId already reviewed a product
+func (db *DB) reviewExists(ctx context.Context, productId int64, userId string) error {
+	var id int64
+	err := db.pool.QueryRow(ctx, "SELECT id FROM reviews WHERE user_id = $1 AND product_id = $2", userId, productId).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to query existing review: %w", err)
+	}
+	return &ReviewExistsError{ReviewID: id}
+}
+
+// lockOwnReview locks a review for the rest of the transaction and returns
+// the product it is about, provided it was written by userId
+func lockOwnReview(ctx context.Context, tx pgx.Tx, id int64, userId string) (int64, error) {
+	var productId int64
+	var author string
+	err := tx.QueryRow(ctx, "SELECT product_id, user_id FROM reviews WHERE id = $1 FOR UPDATE", id).Scan(&productId, &author)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("review with id %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query review: %w", err)
+	}
+	if author != userId {
+		return 0, fmt.Errorf("review with id %d belongs to another user: %w", id, ErrForbidden)
+	}
+	return productId, nil
 }
@@ -1,8 +1,10 @@
 package db
 
 import (
+	"catalogapi/money"
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -36,32 +38,119 @@ func TestGetProducts(t *testing.T) {
 	defer cleanup()
 
 	testProducts := []Product{
-		{ID: 1, Name: "Test Product 1", Price: 19.99, Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
-		{ID: 2, Name: "Test Product 2", Price: 29.99, Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
-		{ID: 3, Name: "Test Product 3", Price: 39.99, Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
 	}
 
 	err := PopulateTestData(ctx, db, "products", testProducts)
 	require.NoError(t, err)
 
-	products, err := db.GetProducts(ctx)
+	products, next, err := db.GetProducts(ctx, ProductFilter{}, Page{})
 	require.NoError(t, err)
 
 	require.Len(t, products, len(testProducts))
+	require.Empty(t, next)
 	for i, p := range products {
 		tp := testProducts[i]
 		validateProduct(t, p, tp)
 	}
 }
 
+func TestGetProductsPagination(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 4, Name: "Test Product 4", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 4"},
+		{ID: 5, Name: "Test Product 5", Price: money.MustParse("59.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 5"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	var all []Product
+	page := Page{Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, len(testProducts), "pagination did not terminate")
+		products, next, err := db.GetProducts(ctx, ProductFilter{}, page)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(products), page.Limit)
+		all = append(all, products...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+
+	require.Len(t, all, len(testProducts))
+	for i, p := range all {
+		validateProduct(t, p, testProducts[i])
+	}
+
+	_, _, err = db.GetProducts(ctx, ProductFilter{}, Page{Cursor: "not-a-cursor"})
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestGetProductsFiltered(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Desk Lamp", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Name: "Floor Lamp", Price: money.MustParse("59.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2", CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 3, Name: "Desk", Price: money.MustParse("99.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3", CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 4, Name: "Chair", Price: money.MustParse("59.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 4", CreatedAt: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	minPrice, maxPrice := money.MustParse("20", "USD"), money.MustParse("60", "USD")
+	createdAfter := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		filter   ProductFilter
+		expected []int64
+	}{
+		{name: "price range", filter: ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}, expected: []int64{2, 4}},
+		{name: "name substring", filter: ProductFilter{Query: "lamp"}, expected: []int64{1, 2}},
+		{name: "created after", filter: ProductFilter{CreatedAfter: &createdAfter}, expected: []int64{2, 3, 4}},
+		{name: "sort by price then newest", filter: ProductFilter{Sort: []SortField{{Field: "price"}, {Field: "created_at", Desc: true}}}, expected: []int64{1, 4, 2, 3}},
+		{name: "sort by name descending", filter: ProductFilter{Sort: []SortField{{Field: "name", Desc: true}}}, expected: []int64{2, 3, 1, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Walk the listing one row at a time to exercise the keyset cursor
+			var ids []int64
+			page := Page{Limit: 1}
+			for range len(testProducts) + 1 {
+				products, next, err := db.GetProducts(ctx, tt.filter, page)
+				require.NoError(t, err)
+				for _, p := range products {
+					ids = append(ids, p.ID)
+				}
+				if next == "" {
+					break
+				}
+				page.Cursor = next
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
 func TestGetProduct(t *testing.T) {
 	db, cleanup, ctx := setupEmptyDB(t)
 	defer cleanup()
 
 	testProducts := []Product{
-		{ID: 1, Name: "Test Product 1", Price: 19.99, Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
-		{ID: 2, Name: "Test Product 2", Price: 29.99, Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
-		{ID: 3, Name: "Test Product 3", Price: 39.99, Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
 	}
 
 	err := PopulateTestData(ctx, db, "products", testProducts)
@@ -72,6 +161,9 @@ func TestGetProduct(t *testing.T) {
 		require.NoError(t, err)
 		validateProduct(t, p, tp)
 	}
+
+	_, err = db.GetProduct(ctx, 42)
+	require.ErrorIs(t, err, ErrNotFound)
 }
 
 func TestPostReview(t *testing.T) {
@@ -79,9 +171,9 @@ func TestPostReview(t *testing.T) {
 	defer cleanup()
 
 	testProducts := []Product{
-		{ID: 1, Name: "Test Product 1", Price: 19.99, Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
-		{ID: 2, Name: "Test Product 2", Price: 29.99, Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
-		{ID: 3, Name: "Test Product 3", Price: 39.99, Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
 	}
 
 	err := PopulateTestData(ctx, db, "products", testProducts)
@@ -94,31 +186,176 @@ func TestPostReview(t *testing.T) {
 	}
 
 	for _, tr := range testReviews {
-		r, err := db.PostReview(ctx, ClientReview{ProductID: tr.ProductID, ReviewTitle: tr.ReviewTitle, ReviewContent: tr.ReviewContent, Stars: tr.Stars}, "1")
+		r, err := db.PostReview(ctx, ClientReview{ProductID: tr.ProductID, ReviewTitle: tr.ReviewTitle, ReviewContent: tr.ReviewContent, Stars: tr.Stars}, "1", ReviewApproved)
 		require.NoError(t, err)
 		validateReview(t, r, tr)
 	}
 }
 
+func TestPostReviewTwice(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	first, err := db.PostReview(ctx, ClientReview{ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 4}, "1", ReviewApproved)
+	require.NoError(t, err)
+
+	_, err = db.PostReview(ctx, ClientReview{ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 1}, "1", ReviewApproved)
+	require.ErrorIs(t, err, ErrConflict)
+	var exists *ReviewExistsError
+	require.ErrorAs(t, err, &exists)
+	assert.Equal(t, first.ID, exists.ReviewID)
+
+	// Other users can still review the product
+	_, err = db.PostReview(ctx, ClientReview{ProductID: 1, ReviewTitle: "Title 3", ReviewContent: "Content 3", Stars: 1}, "2", ReviewApproved)
+	require.NoError(t, err)
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), product.ReviewCount)
+}
+
+func TestUpsertReview(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	review, created, err := db.UpsertReview(ctx, 1, "1", ReviewEdit{ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 2}, ReviewApproved)
+	require.NoError(t, err)
+	assert.True(t, created)
+	validateReview(t, review, Review{ID: review.ID, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 2})
+
+	replaced, created, err := db.UpsertReview(ctx, 1, "1", ReviewEdit{ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 5}, ReviewApproved)
+	require.NoError(t, err)
+	assert.False(t, created)
+	validateReview(t, replaced, Review{ID: review.ID, UserId: "1", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 5})
+	assert.True(t, replaced.UpdatedAt.After(review.UpdatedAt))
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), product.ReviewCount)
+	assert.Equal(t, 5.0, product.AverageRating)
+
+	_, _, err = db.UpsertReview(ctx, 42, "1", ReviewEdit{ReviewTitle: "Missing", ReviewContent: "Missing", Stars: 1}, ReviewApproved)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUpdateReview(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	posted, err := db.PostReview(ctx, ClientReview{ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 2}, "1", ReviewApproved)
+	require.NoError(t, err)
+
+	prior := RatingPrior{Mean: 3, Weight: 2}
+	summary, err := db.GetRatingSummary(ctx, 1, prior)
+	require.NoError(t, err)
+	require.NotNil(t, summary.Mean)
+	assert.Equal(t, 2.0, *summary.Mean)
+
+	edited, err := db.UpdateReview(ctx, posted.ID, "1", ReviewEdit{ReviewTitle: "Edited", ReviewContent: "Edited content", Stars: 5}, ReviewApproved)
+	require.NoError(t, err)
+	validateReview(t, edited, Review{ID: posted.ID, UserId: "1", ProductID: 1, ReviewTitle: "Edited", ReviewContent: "Edited content", Stars: 5})
+	assert.True(t, edited.UpdatedAt.After(posted.UpdatedAt), "updated_at is bumped")
+	assert.Equal(t, posted.CreatedAt, edited.CreatedAt)
+
+	// The edit drops the cached summary and moves the product aggregates
+	summary, err = db.GetRatingSummary(ctx, 1, prior)
+	require.NoError(t, err)
+	require.NotNil(t, summary.Mean)
+	assert.Equal(t, 5.0, *summary.Mean)
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, product.AverageRating)
+
+	_, err = db.UpdateReview(ctx, posted.ID, "2", ReviewEdit{ReviewTitle: "Hijacked", ReviewContent: "Hijacked", Stars: 1}, ReviewApproved)
+	require.ErrorIs(t, err, ErrForbidden)
+	_, err = db.UpdateReview(ctx, 42, "1", ReviewEdit{ReviewTitle: "Missing", ReviewContent: "Missing", Stars: 1}, ReviewApproved)
+	require.ErrorIs(t, err, ErrNotFound)
+	variantId := int64(42)
+	_, err = db.UpdateReview(ctx, posted.ID, "1", ReviewEdit{VariantID: &variantId, ReviewTitle: "Title", ReviewContent: "Content", Stars: 1}, ReviewApproved)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDeleteReview(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []Review{
+		{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
+		{ID: 2, UserId: "2", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 5},
+	}
+	err = PopulateTestData(ctx, db, "reviews", testReviews)
+	require.NoError(t, err)
+
+	prior := RatingPrior{Mean: 3, Weight: 2}
+	summary, err := db.GetRatingSummary(ctx, 1, prior)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), summary.Count)
+
+	err = db.DeleteReview(ctx, 1, "2")
+	require.ErrorIs(t, err, ErrForbidden)
+	err = db.DeleteReview(ctx, 42, "1")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = db.DeleteReview(ctx, 1, "1")
+	require.NoError(t, err)
+	err = db.DeleteReview(ctx, 1, "1")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	reviews, _, err := db.GetProductReviews(ctx, 1, Page{})
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	validateReview(t, reviews[0], testReviews[1])
+
+	// The delete drops the cached summary
+	summary, err = db.GetRatingSummary(ctx, 1, prior)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.Count)
+	assert.Equal(t, map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 1}, summary.Histogram)
+}
+
 func TestGetProductReviews(t *testing.T) {
 	db, cleanup, ctx := setupEmptyDB(t)
 	defer cleanup()
 
 	testProducts := []Product{
-		{ID: 1, Name: "Test Product 1", Price: 19.99, Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
-		{ID: 2, Name: "Test Product 2", Price: 29.99, Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
-		{ID: 3, Name: "Test Product 3", Price: 39.99, Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
 	}
 	err := PopulateTestData(ctx, db, "products", testProducts)
 	require.NoError(t, err)
 
 	testReviews := []Review{
 		{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
-		{ID: 2, UserId: "1", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
+		{ID: 2, UserId: "2", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
 		{ID: 3, UserId: "1", ProductID: 2, ReviewTitle: "Title 3", ReviewContent: "Content 3", Stars: 3},
-		{ID: 4, UserId: "1", ProductID: 2, ReviewTitle: "Title 4", ReviewContent: "Content 4", Stars: 1},
+		{ID: 4, UserId: "2", ProductID: 2, ReviewTitle: "Title 4", ReviewContent: "Content 4", Stars: 1},
 		{ID: 5, UserId: "1", ProductID: 3, ReviewTitle: "Title 5", ReviewContent: "Content 5", Stars: 2},
-		{ID: 6, UserId: "1", ProductID: 3, ReviewTitle: "Title 6", ReviewContent: "Content 6", Stars: 3},
+		{ID: 6, UserId: "2", ProductID: 3, ReviewTitle: "Title 6", ReviewContent: "Content 6", Stars: 3},
 	}
 	err = PopulateTestData(ctx, db, "reviews", testReviews)
 	require.NoError(t, err)
@@ -131,27 +368,27 @@ func TestGetProductReviews(t *testing.T) {
 			productId: 1,
 			expected: []Review{
 				{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
-				{ID: 2, UserId: "1", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
+				{ID: 2, UserId: "2", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
 			},
 		},
 		{
 			productId: 2,
 			expected: []Review{
 				{ID: 3, UserId: "1", ProductID: 2, ReviewTitle: "Title 3", ReviewContent: "Content 3", Stars: 3},
-				{ID: 4, UserId: "1", ProductID: 2, ReviewTitle: "Title 4", ReviewContent: "Content 4", Stars: 1},
+				{ID: 4, UserId: "2", ProductID: 2, ReviewTitle: "Title 4", ReviewContent: "Content 4", Stars: 1},
 			},
 		},
 		{
 			productId: 3,
 			expected: []Review{
 				{ID: 5, UserId: "1", ProductID: 3, ReviewTitle: "Title 5", ReviewContent: "Content 5", Stars: 2},
-				{ID: 6, UserId: "1", ProductID: 3, ReviewTitle: "Title 6", ReviewContent: "Content 6", Stars: 3},
+				{ID: 6, UserId: "2", ProductID: 3, ReviewTitle: "Title 6", ReviewContent: "Content 6", Stars: 3},
 			},
 		},
 	}
 
 	for _, tt := range tests {
-		reviews, err := db.GetProductReviews(ctx, tt.productId)
+		reviews, _, err := db.GetProductReviews(ctx, tt.productId, Page{})
 		require.NoError(t, err)
 		assert.Len(t, reviews, len(tt.expected))
 		for i, r := range reviews {
@@ -160,6 +397,176 @@ func TestGetProductReviews(t *testing.T) {
 		}
 	}
 
+	_, _, err = db.GetProductReviews(ctx, 42, Page{})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGetProductReviewsPagination(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []Review{
+		{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
+		{ID: 2, UserId: "2", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
+		{ID: 3, UserId: "3", ProductID: 1, ReviewTitle: "Title 3", ReviewContent: "Content 3", Stars: 3},
+	}
+	err = PopulateTestData(ctx, db, "reviews", testReviews)
+	require.NoError(t, err)
+
+	first, next, err := db.GetProductReviews(ctx, 1, Page{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	require.NotEmpty(t, next)
+
+	second, next, err := db.GetProductReviews(ctx, 1, Page{Limit: 2, Cursor: next})
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	require.Empty(t, next)
+
+	for i, r := range append(first, second...) {
+		validateReview(t, r, testReviews[i])
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+
+	for _, tp := range testProducts {
+		p, err := db.CreateProduct(ctx, ClientProduct{Name: tp.Name, Price: tp.Price, Image: tp.Image, Description: tp.Description})
+		require.NoError(t, err)
+		validateProduct(t, p, tp)
+
+		stored, err := db.GetProduct(ctx, p.ID)
+		require.NoError(t, err)
+		validateProduct(t, stored, tp)
+	}
+}
+
+func TestUpdateProduct(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	expected := Product{ID: 1, Name: "Updated Product", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/300", Description: "Updated Description"}
+	p, err := db.UpdateProduct(ctx, 1, ClientProduct{Name: expected.Name, Price: expected.Price, Image: expected.Image, Description: expected.Description}, "admin1")
+	require.NoError(t, err)
+	validateProduct(t, p, expected)
+
+	_, err = db.UpdateProduct(ctx, 42, ClientProduct{Name: "Missing", Price: money.MustParse("1", "USD"), Image: "https://via.placeholder.com/150"}, "admin1")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPatchProduct(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	name := "Patched Product"
+	price := money.MustParse("9.99", "USD")
+	p, err := db.PatchProduct(ctx, 1, ProductPatch{Name: &name, Price: &price}, "admin1")
+	require.NoError(t, err)
+
+	expected := testProducts[0]
+	expected.Name = name
+	expected.Price = price
+	validateProduct(t, p, expected)
+
+	_, err = db.PatchProduct(ctx, 42, ProductPatch{Name: &name}, "admin1")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestArchiveProduct(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	archived, err := db.ArchiveProduct(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, archived.ArchivedAt)
+
+	// Archiving again keeps the original archive time
+	again, err := db.ArchiveProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, archived.ArchivedAt, again.ArchivedAt)
+
+	// Archived products are still readable by id
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.NotNil(t, product.ArchivedAt)
+
+	products, _, err := db.GetProducts(ctx, ProductFilter{}, Page{})
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	assert.Equal(t, int64(2), products[0].ID)
+
+	products, _, err = db.GetProducts(ctx, ProductFilter{IncludeArchived: true}, Page{})
+	require.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	restored, err := db.RestoreProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Nil(t, restored.ArchivedAt)
+
+	products, _, err = db.GetProducts(ctx, ProductFilter{}, Page{})
+	require.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	_, err = db.ArchiveProduct(ctx, 42)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = db.RestoreProduct(ctx, 42)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestArchiveProductWithReviews(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []Review{
+		{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
+	}
+	err = PopulateTestData(ctx, db, "reviews", testReviews)
+	require.NoError(t, err)
+
+	_, err = db.ArchiveProduct(ctx, 1)
+	require.NoError(t, err)
+
+	reviews, _, err := db.GetProductReviews(ctx, 1, Page{})
+	require.NoError(t, err)
+	assert.Len(t, reviews, 1)
 }
 
 // ===========================================
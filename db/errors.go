@@ -0,0 +1,75 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Sentinel errors returned by DB methods, callers should match them with errors.Is
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write violates a unique or foreign key constraint
+	ErrConflict = errors.New("conflict")
+	// ErrForbidden is returned when a user writes to a row they do not own
+	ErrForbidden = errors.New("forbidden")
+	// ErrInsufficientStock is returned when a reservation asks for more than is available
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrUnsupportedCurrency is returned when prices are requested in a currency without an exchange rate
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrInvalidCursor is returned when a page cursor cannot be decoded
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidFilter is returned when a listing filter or sort cannot be applied
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// ReviewExistsError is returned when a user posts a second review of the
+// same product. It matches ErrConflict and carries the id of the review the
+// user already wrote.
+type ReviewExistsError struct {
+	ReviewID int64
+}
+
+func (e *ReviewExistsError) Error() string {
+	return fmt.Sprintf("%s: review with id %d already exists", ErrConflict, e.ReviewID)
+}
+
+func (e *ReviewExistsError) Unwrap() error { return ErrConflict }
+
+// Postgres error codes we translate into sentinel errors
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	checkViolation      = "23514"
+)
+
+// translateError maps driver errors onto the package sentinel errors,
+// anything it does not recognise is returned untouched
+func translateError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation, foreignKeyViolation:
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		}
+	}
+	return err
+}
+
+// isForeignKeyViolation reports whether err was caused by a missing referenced row
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+// isCheckViolation reports whether err was caused by a failed CHECK constraint
+func isCheckViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == checkViolation
+}
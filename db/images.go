@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ProductImage is an image uploaded for a product. The file itself lives in
+// blob storage under Key, URL is filled in by the server.
+type ProductImage struct {
+	ID          int64     `db:"id" json:"id"`
+	ProductID   int64     `db:"product_id" json:"product_id"`
+	Key         string    `db:"key" json:"-"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	Size        int64     `db:"size" json:"size"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	// ProcessedAt is set once the renditions of the image were generated
+	ProcessedAt *time.Time `db:"processed_at" json:"-"`
+	URL         string     `db:"-" json:"url"`
+	// Srcset lists the resized copies of the image, empty until they are generated
+	Srcset []ImageRendition `db:"-" json:"srcset"`
+}
+
+// ImageRendition is a resized copy of a product image in one format
+type ImageRendition struct {
+	ID          int64     `db:"id" json:"-"`
+	ImageID     int64     `db:"image_id" json:"-"`
+	Width       int       `db:"width" json:"width"`
+	Format      string    `db:"format" json:"format"`
+	Key         string    `db:"key" json:"-"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	Size        int64     `db:"size" json:"size"`
+	CreatedAt   time.Time `db:"created_at" json:"-"`
+	URL         string    `db:"-" json:"url"`
+}
+
+const (
+	productImageColumns   = "id, product_id, key, content_type, size, created_at, processed_at"
+	imageRenditionColumns = "id, image_id, width, format, key, content_type, size, created_at"
+)
+
+// GetProductImages returns all images of a product in upload order
+func (db *DB) GetProductImages(ctx context.Context, productID int64) ([]ProductImage, error) {
+	rows, err := db.pool.Query(ctx,
+		"SELECT "+productImageColumns+" FROM product_images WHERE product_id = $1 ORDER BY id",
+		productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images: %w", err)
+	}
+	images, err := pgx.CollectRows(rows, pgx.RowToStructByName[ProductImage])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images: %w", err)
+	}
+	return images, nil
+}
+
+// CreateProductImage records an image already stored under key
+func (db *DB) CreateProductImage(ctx context.Context, productID int64, key, contentType string, size int64) (ProductImage, error) {
+	rows, err := db.pool.Query(ctx,
+		`INSERT INTO product_images (product_id, key, content_type, size)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+productImageColumns,
+		productID, key, contentType, size)
+	if err != nil {
+		return ProductImage{}, fmt.Errorf("failed to insert image: %w", err)
+	}
+	image, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ProductImage])
+	if isForeignKeyViolation(err) {
+		return ProductImage{}, fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+	if err != nil {
+		return ProductImage{}, fmt.Errorf("failed to insert image: %w", translateError(err))
+	}
+	return image, nil
+}
+
+// GetUnprocessedImages returns up to limit images whose renditions have not
+// been generated yet, oldest first
+func (db *DB) GetUnprocessedImages(ctx context.Context, limit int) ([]ProductImage, error) {
+	rows, err := db.pool.Query(ctx,
+		"SELECT "+productImageColumns+" FROM product_images WHERE processed_at IS NULL ORDER BY id LIMIT $1",
+		limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images: %w", err)
+	}
+	images, err := pgx.CollectRows(rows, pgx.RowToStructByName[ProductImage])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images: %w", err)
+	}
+	return images, nil
+}
+
+// GetImageRenditions returns the renditions of the given images ordered by
+// image, format and width
+func (db *DB) GetImageRenditions(ctx context.Context, imageIDs []int64) ([]ImageRendition, error) {
+	rows, err := db.pool.Query(ctx,
+		"SELECT "+imageRenditionColumns+" FROM product_image_renditions WHERE image_id = ANY($1) ORDER BY image_id, format DESC, width",
+		imageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query renditions: %w", err)
+	}
+	renditions, err := pgx.CollectRows(rows, pgx.RowToStructByName[ImageRendition])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query renditions: %w", err)
+	}
+	return renditions, nil
+}
+
+// SaveImageRenditions records the renditions generated for an image and
+// marks it processed. Renditions already recorded, for example by another
+// instance processing the same image, are left alone. ErrNotFound means the
+// image was deleted in the meantime.
+func (db *DB) SaveImageRenditions(ctx context.Context, imageID int64, renditions []ImageRendition) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE product_images SET processed_at = now() WHERE id = $1", imageID)
+	if err != nil {
+		return fmt.Errorf("failed to update image: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("image with id %d %w", imageID, ErrNotFound)
+	}
+
+	for _, r := range renditions {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO product_image_renditions (image_id, width, format, key, content_type, size)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (image_id, width, format) DO NOTHING`,
+			imageID, r.Width, r.Format, r.Key, r.ContentType, r.Size)
+		if err != nil {
+			return fmt.Errorf("failed to insert rendition: %w", translateError(err))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit renditions: %w", err)
+	}
+	return nil
+}
+
+// DeleteProductImage removes an image and returns it so the caller can
+// delete the stored file
+func (db *DB) DeleteProductImage(ctx context.Context, productID, id int64) (ProductImage, error) {
+	rows, err := db.pool.Query(ctx,
+		"DELETE FROM product_images WHERE product_id = $1 AND id = $2 RETURNING "+productImageColumns,
+		productID, id)
+	if err != nil {
+		return ProductImage{}, fmt.Errorf("failed to delete image: %w", err)
+	}
+	image, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ProductImage])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ProductImage{}, fmt.Errorf("image with id %d of product %d %w", id, productID, ErrNotFound)
+	}
+	if err != nil {
+		return ProductImage{}, fmt.Errorf("failed to delete image: %w", err)
+	}
+	return image, nil
+}
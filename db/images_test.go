@@ -0,0 +1,96 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductImages(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	front, err := db.CreateProductImage(ctx, 1, "front.png", "image/png", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "front.png", front.Key)
+	_, err = db.CreateProductImage(ctx, 1, "back.jpg", "image/jpeg", 2048)
+	require.NoError(t, err)
+
+	_, err = db.CreateProductImage(ctx, 1, "front.png", "image/png", 1024)
+	require.ErrorIs(t, err, ErrConflict)
+	_, err = db.CreateProductImage(ctx, 42, "side.png", "image/png", 1024)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	images, err := db.GetProductImages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, images, 2)
+	assert.Equal(t, "front.png", images[0].Key)
+	assert.Equal(t, "back.jpg", images[1].Key)
+
+	deleted, err := db.DeleteProductImage(ctx, 1, front.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "front.png", deleted.Key)
+
+	_, err = db.DeleteProductImage(ctx, 1, front.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Archiving a product keeps its images so it can be restored
+	_, err = db.ArchiveProduct(ctx, 1)
+	require.NoError(t, err)
+	images, err = db.GetProductImages(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, images, 1)
+}
+
+func TestImageRenditions(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	front, err := db.CreateProductImage(ctx, 1, "front.png", "image/png", 1024)
+	require.NoError(t, err)
+	back, err := db.CreateProductImage(ctx, 1, "back.png", "image/png", 1024)
+	require.NoError(t, err)
+
+	images, err := db.GetUnprocessedImages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.Equal(t, front.ID, images[0].ID)
+
+	renditions := []ImageRendition{
+		{Width: 150, Format: "jpeg", Key: "front-150w.jpg", ContentType: "image/jpeg", Size: 100},
+		{Width: 150, Format: "webp", Key: "front-150w.webp", ContentType: "image/webp", Size: 80},
+	}
+	err = db.SaveImageRenditions(ctx, front.ID, renditions)
+	require.NoError(t, err)
+	// Saving the same renditions again is a no-op
+	err = db.SaveImageRenditions(ctx, front.ID, renditions)
+	require.NoError(t, err)
+
+	images, err = db.GetUnprocessedImages(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.Equal(t, back.ID, images[0].ID)
+
+	saved, err := db.GetImageRenditions(ctx, []int64{front.ID, back.ID})
+	require.NoError(t, err)
+	require.Len(t, saved, 2)
+	assert.Equal(t, "webp", saved[0].Format)
+	assert.Equal(t, "jpeg", saved[1].Format)
+
+	err = db.SaveImageRenditions(ctx, 42, renditions)
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"catalogapi/money"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ProductImport is one row of a bulk product import. Rows are matched to
+// existing products by ID first, then by ExternalID and then by the SKU of
+// one of the product's variants. Unmatched rows create a product, and a SKU
+// that does not exist yet is added to the product as a variant so that the
+// next import matches it again.
+type ProductImport struct {
+	// ID matches the row to an existing product, as exported. An unknown
+	// ID is an error unless ExternalID or SKU is set too.
+	ID         int64  `json:"id"`
+	ExternalID string `json:"external_id"`
+	SKU        string `json:"sku"`
+	ClientProduct
+}
+
+// ProductExport is one row of a bulk product export. Its JSON is accepted
+// back by the import, which matches rows to products by id and ignores the
+// created_at field.
+type ProductExport struct {
+	ID          int64       `db:"id" json:"id"`
+	ExternalID  *string     `db:"external_id" json:"external_id"`
+	Name        string      `db:"name" json:"name"`
+	Description string      `db:"description" json:"description"`
+	Price       money.Money `db:"price" json:"price"`
+	Image       string      `db:"image" json:"image"`
+	Attributes  Attributes  `db:"attributes" json:"attributes"`
+	CreatedAt   time.Time   `db:"created_at" json:"created_at"`
+}
+
+// ProductImporter upserts imported rows inside a single transaction. Each
+// row runs in its own savepoint so a failing row does not undo the others.
+// Nothing is written until Commit, and a dry run never commits.
+type ProductImporter struct {
+	tx     pgx.Tx
+	userID string
+	dryRun bool
+}
+
+// BeginProductImport starts an import made by userID, the importer must be
+// closed once done
+func (db *DB) BeginProductImport(ctx context.Context, userID string, dryRun bool) (*ProductImporter, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return &ProductImporter{tx: tx, userID: userID, dryRun: dryRun}, nil
+}
+
+// Upsert creates or updates the product of one row and reports whether it
+// was created. On error the row is rolled back and the import can go on.
+func (im *ProductImporter) Upsert(ctx context.Context, row ProductImport) (bool, error) {
+	savepoint, err := im.tx.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin savepoint: %w", err)
+	}
+	defer savepoint.Rollback(ctx)
+
+	created, err := upsertImportedProduct(ctx, savepoint, row, im.userID)
+	if err != nil {
+		return false, err
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return created, nil
+}
+
+// Commit writes the imported rows, unless this is a dry run
+func (im *ProductImporter) Commit(ctx context.Context) error {
+	if im.dryRun {
+		return im.tx.Rollback(ctx)
+	}
+	if err := im.tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// Close discards the import if it was not committed
+func (im *ProductImporter) Close(ctx context.Context) {
+	im.tx.Rollback(ctx)
+}
+
+// ExportProducts calls fn with every product that is not archived, ordered
+// by id. Rows are read from the database as fn consumes them, so the catalog
+// is never held in memory as a whole.
+func (db *DB) ExportProducts(ctx context.Context, fn func(ProductExport) error) error {
+	rows, err := db.pool.Query(ctx,
+		`SELECT id, external_id, name, description, price, image, attributes, created_at
+		FROM products WHERE archived_at IS NULL ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		product, err := pgx.RowToStructByName[ProductExport](rows)
+		if err != nil {
+			return fmt.Errorf("failed to serialize product: %w", err)
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query products: %w", err)
+	}
+	return nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func upsertImportedProduct(ctx context.Context, tx pgx.Tx, row ProductImport, userID string) (bool, error) {
+	var byID, byExternalID, bySKU *importMatch
+	var err error
+	if row.ID != 0 {
+		byID, err = matchImportedProduct(ctx, tx,
+			"SELECT id, price FROM products WHERE id = $1 FOR UPDATE", row.ID)
+		if err != nil {
+			return false, err
+		}
+		if byID == nil && row.ExternalID == "" && row.SKU == "" {
+			return false, fmt.Errorf("product with id %d %w", row.ID, ErrNotFound)
+		}
+	}
+	if row.ExternalID != "" {
+		byExternalID, err = matchImportedProduct(ctx, tx,
+			"SELECT id, price FROM products WHERE external_id = $1 FOR UPDATE", row.ExternalID)
+		if err != nil {
+			return false, err
+		}
+	}
+	if row.SKU != "" {
+		bySKU, err = matchImportedProduct(ctx, tx,
+			`SELECT p.id, p.price FROM products p
+			JOIN product_variants v ON v.product_id = p.id
+			WHERE v.sku = $1
+			FOR UPDATE OF p`, row.SKU)
+		if err != nil {
+			return false, err
+		}
+	}
+	if byID != nil && byExternalID != nil && byID.id != byExternalID.id {
+		return false, fmt.Errorf("%w: id %d and external_id %q belong to different products", ErrConflict, row.ID, row.ExternalID)
+	}
+	if byID != nil && bySKU != nil && byID.id != bySKU.id {
+		return false, fmt.Errorf("%w: id %d and sku %q belong to different products", ErrConflict, row.ID, row.SKU)
+	}
+	if byExternalID != nil && bySKU != nil && byExternalID.id != bySKU.id {
+		return false, fmt.Errorf("%w: external_id %q and sku %q belong to different products", ErrConflict, row.ExternalID, row.SKU)
+	}
+
+	var externalID *string
+	if row.ExternalID != "" {
+		externalID = &row.ExternalID
+	}
+
+	match := byID
+	if match == nil {
+		match = byExternalID
+	}
+	if match == nil {
+		match = bySKU
+	}
+	var productID int64
+	if match != nil {
+		productID = match.id
+		// Rows without attributes, like most CSV rows, keep the current
+		// ones. The plain map is sent as NULL when nil, unlike Attributes.
+		_, err = tx.Exec(ctx,
+			`UPDATE products SET name = $2, price = $3, image = $4, description = $5,
+				external_id = COALESCE($6, external_id), attributes = COALESCE($7, attributes)
+			WHERE id = $1`,
+			productID, row.Name, row.Price, row.Image, row.Description, externalID, map[string]string(row.Attributes))
+		if err != nil {
+			return false, fmt.Errorf("failed to update product: %w", translateError(err))
+		}
+		if err := recordPriceChange(ctx, tx, productID, match.price, row.Price, userID, nil); err != nil {
+			return false, err
+		}
+	} else {
+		err = tx.QueryRow(ctx,
+			`INSERT INTO products (name, price, image, description, external_id, attributes)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id`,
+			row.Name, row.Price, row.Image, row.Description, externalID, row.Attributes).Scan(&productID)
+		if err != nil {
+			return false, fmt.Errorf("failed to insert product: %w", translateError(err))
+		}
+	}
+
+	if row.SKU != "" && bySKU == nil {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO product_variants (product_id, sku, options) VALUES ($1, $2, '{}')",
+			productID, row.SKU)
+		if err != nil {
+			return false, fmt.Errorf("failed to insert variant: %w", translateError(err))
+		}
+	}
+	return match == nil, nil
+}
+
+// importMatch is an existing product an imported row resolved to
+type importMatch struct {
+	id    int64
+	price money.Money
+}
+
+// matchImportedProduct locks and returns the product selected by query, or
+// nil when there is none
+func matchImportedProduct(ctx context.Context, tx pgx.Tx, query string, arg any) (*importMatch, error) {
+	var m importMatch
+	err := tx.QueryRow(ctx, query, arg).Scan(&m.id, &m.price)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product: %w", err)
+	}
+	return &m, nil
+}
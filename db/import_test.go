@@ -0,0 +1,141 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductImport(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+	_, err = db.CreateVariant(ctx, 1, ClientVariant{SKU: "TS-M"})
+	require.NoError(t, err)
+
+	importer, err := db.BeginProductImport(ctx, "admin1", false)
+	require.NoError(t, err)
+	defer importer.Close(ctx)
+
+	// Matched by the SKU of a variant, the external id is attached to the product
+	created, err := importer.Upsert(ctx, ProductImport{ExternalID: "erp-1", SKU: "TS-M", ClientProduct: ClientProduct{
+		Name: "T-Shirt", Price: money.MustParse("17.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	// Unmatched rows create a product along with a variant for the SKU
+	created, err = importer.Upsert(ctx, ProductImport{ExternalID: "erp-3", SKU: "CAP-1", ClientProduct: ClientProduct{
+		Name: "Cap", Price: money.MustParse("9.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// A row pointing at two different products is rejected without
+	// aborting the import
+	_, err = importer.Upsert(ctx, ProductImport{ExternalID: "erp-1", SKU: "CAP-1", ClientProduct: ClientProduct{
+		Name: "Cap", Price: money.MustParse("9.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.ErrorIs(t, err, ErrConflict)
+
+	created, err = importer.Upsert(ctx, ProductImport{ExternalID: "erp-1", ClientProduct: ClientProduct{
+		Name: "Classic T-Shirt", Price: money.MustParse("17.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	// Exported rows are matched by the product id
+	created, err = importer.Upsert(ctx, ProductImport{ID: 2, ClientProduct: ClientProduct{
+		Name: "Zip Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	_, err = importer.Upsert(ctx, ProductImport{ID: 42, ClientProduct: ClientProduct{
+		Name: "Scarf", Price: money.MustParse("9.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = importer.Upsert(ctx, ProductImport{ID: 2, ExternalID: "erp-1", ClientProduct: ClientProduct{
+		Name: "Zip Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.ErrorIs(t, err, ErrConflict)
+
+	err = importer.Commit(ctx)
+	require.NoError(t, err)
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Classic T-Shirt", product.Name)
+	assert.Equal(t, money.MustParse("17.99", "USD"), product.Price)
+	require.NotNil(t, product.ExternalID)
+	assert.Equal(t, "erp-1", *product.ExternalID)
+
+	product, err = db.GetProduct(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Zip Hoodie", product.Name)
+	assert.Nil(t, product.ExternalID)
+
+	history, _, err := db.GetPriceHistory(ctx, 1, Page{})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "admin1", history[0].ChangedBy)
+
+	// Archived products are left out of the export
+	_, err = db.ArchiveProduct(ctx, 2)
+	require.NoError(t, err)
+
+	var exported []ProductExport
+	err = db.ExportProducts(ctx, func(p ProductExport) error {
+		exported = append(exported, p)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	assert.Equal(t, int64(1), exported[0].ID)
+	assert.Equal(t, "Cap", exported[1].Name)
+	require.NotNil(t, exported[1].ExternalID)
+	assert.Equal(t, "erp-3", *exported[1].ExternalID)
+
+	variants, err := db.GetProductVariants(ctx, exported[1].ID)
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	assert.Equal(t, "CAP-1", variants[0].SKU)
+}
+
+func TestProductImportDryRun(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	importer, err := db.BeginProductImport(ctx, "admin1", true)
+	require.NoError(t, err)
+	defer importer.Close(ctx)
+
+	created, err := importer.Upsert(ctx, ProductImport{ExternalID: "erp-1", ClientProduct: ClientProduct{
+		Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// Rows of a dry run see the rows before them
+	created, err = importer.Upsert(ctx, ProductImport{ExternalID: "erp-1", ClientProduct: ClientProduct{
+		Name: "T-Shirt", Price: money.MustParse("18.99", "USD"), Image: "https://via.placeholder.com/150",
+	}})
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	err = importer.Commit(ctx)
+	require.NoError(t, err)
+
+	products, _, err := db.GetProducts(ctx, ProductFilter{}, Page{})
+	require.NoError(t, err)
+	assert.Empty(t, products)
+}
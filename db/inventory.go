@@ -0,0 +1,275 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StockLevel is the quantity on hand of a product, or of one of its variants
+// when VariantID is set
+type StockLevel struct {
+	ProductID int64     `db:"product_id" json:"product_id"`
+	VariantID *int64    `db:"variant_id" json:"variant_id"`
+	Quantity  int64     `db:"quantity" json:"quantity"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ClientStock is the payload accepted when setting the stock of a product or variant
+type ClientStock struct {
+	VariantID *int64 `json:"variant_id"`
+	Quantity  int64  `json:"quantity"`
+}
+
+// Reservation holds stock for a user until it is committed, released or expires
+type Reservation struct {
+	ID        int64     `json:"id"`
+	ProductID int64     `json:"product_id"`
+	VariantID *int64    `json:"variant_id"`
+	Quantity  int64     `json:"quantity"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ClientReservation is the payload accepted when reserving stock
+type ClientReservation struct {
+	ProductID int64  `json:"product_id"`
+	VariantID *int64 `json:"variant_id"`
+	Quantity  int64  `json:"quantity"`
+}
+
+// availableStockQuery sums the stock of each inventory row minus its
+// unexpired reservations, it expects a WHERE clause and GROUP BY to be appended
+const availableStockQuery = `
+	SELECT %s, COALESCE(SUM(GREATEST(i.quantity - COALESCE(r.reserved, 0), 0)), 0)
+	FROM inventory i
+	LEFT JOIN LATERAL (
+		SELECT SUM(quantity) AS reserved FROM stock_reservations
+		WHERE inventory_id = i.id AND expires_at > now()
+	) r ON true`
+
+// SetStock sets the quantity on hand of a product, or of one of its variants
+func (db *DB) SetStock(ctx context.Context, productID int64, variantID *int64, quantity int64) (StockLevel, error) {
+	if variantID != nil {
+		var exists bool
+		err := db.pool.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM product_variants WHERE id = $1 AND product_id = $2)",
+			*variantID, productID).Scan(&exists)
+		if err != nil {
+			return StockLevel{}, fmt.Errorf("failed to query variant: %w", err)
+		}
+		if !exists {
+			return StockLevel{}, fmt.Errorf("variant with id %d of product %d %w", *variantID, productID, ErrNotFound)
+		}
+	}
+
+	rows, err := db.pool.Query(ctx,
+		`INSERT INTO inventory (product_id, variant_id, quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, (COALESCE(variant_id, 0)))
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP
+		RETURNING product_id, variant_id, quantity, updated_at`,
+		productID, variantID, quantity)
+	if err != nil {
+		return StockLevel{}, fmt.Errorf("failed to set stock: %w", err)
+	}
+	stock, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[StockLevel])
+	if isForeignKeyViolation(err) {
+		return StockLevel{}, fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+	if err != nil {
+		return StockLevel{}, fmt.Errorf("failed to set stock: %w", translateError(err))
+	}
+	return stock, nil
+}
+
+// ReserveStock holds quantity units of a product or variant for userID until
+// ttl elapses. The inventory row is locked while the active reservations are
+// summed, so concurrent reservations are serialized and can never oversell.
+func (db *DB) ReserveStock(ctx context.Context, userID string, productID int64, variantID *int64, quantity int64, ttl time.Duration) (Reservation, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var inventoryID, onHand int64
+	// Archived products can no longer be reserved, even with stock left
+	err = tx.QueryRow(ctx,
+		`SELECT i.id, i.quantity FROM inventory i
+		JOIN products p ON p.id = i.product_id
+		WHERE i.product_id = $1 AND COALESCE(i.variant_id, 0) = COALESCE($2, 0) AND p.archived_at IS NULL
+		FOR UPDATE OF i`,
+		productID, variantID).Scan(&inventoryID, &onHand)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Nothing was ever stocked, tell a missing product apart from an empty shelf
+		var exists bool
+		err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND archived_at IS NULL)", productID).Scan(&exists)
+		if err != nil {
+			return Reservation{}, fmt.Errorf("failed to query product: %w", err)
+		}
+		if !exists {
+			return Reservation{}, fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+		}
+		return Reservation{}, fmt.Errorf("%w: 0 available, %d requested", ErrInsufficientStock, quantity)
+	}
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to lock inventory: %w", err)
+	}
+
+	var reserved int64
+	err = tx.QueryRow(ctx,
+		"SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations WHERE inventory_id = $1 AND expires_at > now()",
+		inventoryID).Scan(&reserved)
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to query reservations: %w", err)
+	}
+	if available := max(onHand-reserved, 0); quantity > available {
+		return Reservation{}, fmt.Errorf("%w: %d available, %d requested", ErrInsufficientStock, available, quantity)
+	}
+
+	reservation := Reservation{ProductID: productID, VariantID: variantID, Quantity: quantity}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO stock_reservations (inventory_id, user_id, quantity, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		RETURNING id, expires_at, created_at`,
+		inventoryID, userID, quantity, ttl.Seconds()).Scan(&reservation.ID, &reservation.ExpiresAt, &reservation.CreatedAt)
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to insert reservation: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Reservation{}, fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	return reservation, nil
+}
+
+// CommitReservation turns an unexpired reservation into a sale by removing
+// the reserved units from the stock on hand
+func (db *DB) CommitReservation(ctx context.Context, userID string, id int64) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var inventoryID, quantity int64
+	err = tx.QueryRow(ctx,
+		`DELETE FROM stock_reservations
+		WHERE id = $1 AND user_id = $2 AND expires_at > now()
+		RETURNING inventory_id, quantity`,
+		id, userID).Scan(&inventoryID, &quantity)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("reservation with id %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete reservation: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE inventory SET quantity = quantity - $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		inventoryID, quantity)
+	if isCheckViolation(err) {
+		// The stock on hand was lowered below the reserved amount after reserving
+		return fmt.Errorf("%w: stock dropped below the reserved quantity", ErrInsufficientStock)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update inventory: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	return nil
+}
+
+// ReleaseReservation gives the reserved units back before the reservation expires
+func (db *DB) ReleaseReservation(ctx context.Context, userID string, id int64) error {
+	tag, err := db.pool.Exec(ctx, "DELETE FROM stock_reservations WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reservation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("reservation with id %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteExpiredReservations removes reservations past their expiry and
+// returns how many were deleted. Expired rows already stop counting against
+// the stock, this only keeps the table small.
+func (db *DB) DeleteExpiredReservations(ctx context.Context) (int64, error) {
+	tag, err := db.pool.Exec(ctx, "DELETE FROM stock_reservations WHERE expires_at <= now()")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired reservations: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// productAvailability returns the unreserved stock of each product, summed
+// over the product itself and all of its variants. Products without any
+// inventory are missing from the map.
+func (db *DB) productAvailability(ctx context.Context, productIDs []int64) (map[int64]int64, error) {
+	rows, err := db.pool.Query(ctx,
+		fmt.Sprintf(availableStockQuery, "i.product_id")+" WHERE i.product_id = ANY($1) GROUP BY i.product_id",
+		productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query availability: %w", err)
+	}
+	return collectAvailability(rows)
+}
+
+// variantAvailability returns the unreserved stock of each variant of a product
+func (db *DB) variantAvailability(ctx context.Context, productID int64) (map[int64]int64, error) {
+	rows, err := db.pool.Query(ctx,
+		fmt.Sprintf(availableStockQuery, "i.variant_id")+
+			" WHERE i.product_id = $1 AND i.variant_id IS NOT NULL GROUP BY i.variant_id",
+		productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query availability: %w", err)
+	}
+	return collectAvailability(rows)
+}
+
+// attachAvailability fills the stock fields of each product in place
+func (db *DB) attachAvailability(ctx context.Context, products []*Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	available, err := db.productAvailability(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range products {
+		p.QuantityAvailable = available[p.ID]
+		p.InStock = p.QuantityAvailable > 0
+	}
+	return nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func collectAvailability(rows pgx.Rows) (map[int64]int64, error) {
+	defer rows.Close()
+	available := make(map[int64]int64)
+	for rows.Next() {
+		var id, quantity int64
+		if err := rows.Scan(&id, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan availability: %w", err)
+		}
+		available[id] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query availability: %w", err)
+	}
+	return available, nil
+}
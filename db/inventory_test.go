@@ -0,0 +1,254 @@
+package db
+
+import (
+	"catalogapi/money"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetStock(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testVariants := []Variant{
+		{ID: 1, ProductID: 1, SKU: "TS-S", Options: map[string]string{"size": "S"}},
+		{ID: 2, ProductID: 2, SKU: "HD-M", Options: map[string]string{"size": "M"}},
+	}
+	err = PopulateTestData(ctx, db, "product_variants", testVariants)
+	require.NoError(t, err)
+
+	stock, err := db.SetStock(ctx, 1, nil, 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), stock.Quantity)
+	assert.Nil(t, stock.VariantID)
+
+	// Setting the stock again replaces the quantity instead of adding a row
+	stock, err = db.SetStock(ctx, 1, nil, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stock.Quantity)
+
+	stock, err = db.SetStock(ctx, 1, ptr(int64(1)), 7)
+	require.NoError(t, err)
+	require.NotNil(t, stock.VariantID)
+	assert.Equal(t, int64(1), *stock.VariantID)
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), product.QuantityAvailable)
+	assert.True(t, product.InStock)
+
+	product, err = db.GetProduct(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), product.QuantityAvailable)
+	assert.False(t, product.InStock)
+
+	// The variant belongs to another product
+	_, err = db.SetStock(ctx, 1, ptr(int64(2)), 1)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.SetStock(ctx, 42, nil, 1)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReserveStock(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	_, err = db.SetStock(ctx, 1, nil, 5)
+	require.NoError(t, err)
+
+	reservation, err := db.ReserveStock(ctx, "user1", 1, nil, 3, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), reservation.Quantity)
+	assert.True(t, reservation.ExpiresAt.After(reservation.CreatedAt))
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), product.QuantityAvailable)
+
+	_, err = db.ReserveStock(ctx, "user2", 1, nil, 3, time.Minute)
+	require.ErrorIs(t, err, ErrInsufficientStock)
+
+	// Product 2 exists but was never stocked
+	_, err = db.ReserveStock(ctx, "user1", 2, nil, 1, time.Minute)
+	require.ErrorIs(t, err, ErrInsufficientStock)
+
+	_, err = db.ReserveStock(ctx, "user1", 42, nil, 1, time.Minute)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReserveStockExpiry(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	_, err = db.SetStock(ctx, 1, nil, 2)
+	require.NoError(t, err)
+
+	expiring, err := db.ReserveStock(ctx, "user1", 1, nil, 2, 500*time.Millisecond)
+	require.NoError(t, err)
+
+	_, err = db.ReserveStock(ctx, "user2", 1, nil, 1, time.Minute)
+	require.ErrorIs(t, err, ErrInsufficientStock)
+
+	time.Sleep(time.Second)
+
+	// The expired reservation no longer holds any stock
+	_, err = db.ReserveStock(ctx, "user2", 1, nil, 1, time.Minute)
+	require.NoError(t, err)
+
+	err = db.CommitReservation(ctx, "user1", expiring.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	deleted, err := db.DeleteExpiredReservations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestReserveStockConcurrently(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	variant, err := db.CreateVariant(ctx, 1, ClientVariant{SKU: "TS-S"})
+	require.NoError(t, err)
+
+	const stock = 10
+	const buyers = 50
+	_, err = db.SetStock(ctx, 1, &variant.ID, stock)
+	require.NoError(t, err)
+
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		reserved     int
+		insufficient int
+		failures     []error
+	)
+	start := make(chan struct{})
+	for i := range buyers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := db.ReserveStock(ctx, fmt.Sprintf("user%d", i), 1, &variant.ID, 1, time.Minute)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				reserved++
+			case errors.Is(err, ErrInsufficientStock):
+				insufficient++
+			default:
+				failures = append(failures, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	require.Empty(t, failures)
+	assert.Equal(t, stock, reserved)
+	assert.Equal(t, buyers-stock, insufficient)
+
+	variants, err := db.GetProductVariants(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	assert.Equal(t, int64(0), variants[0].QuantityAvailable)
+	assert.False(t, variants[0].InStock)
+}
+
+func TestCommitReservation(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	_, err = db.SetStock(ctx, 1, nil, 5)
+	require.NoError(t, err)
+
+	reservation, err := db.ReserveStock(ctx, "user1", 1, nil, 2, time.Minute)
+	require.NoError(t, err)
+
+	// Only the user holding the reservation can commit it
+	err = db.CommitReservation(ctx, "user2", reservation.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = db.CommitReservation(ctx, "user1", reservation.ID)
+	require.NoError(t, err)
+
+	var quantity int64
+	err = db.pool.QueryRow(ctx, "SELECT quantity FROM inventory WHERE product_id = 1").Scan(&quantity)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), quantity)
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), product.QuantityAvailable)
+
+	err = db.CommitReservation(ctx, "user1", reservation.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReleaseReservation(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	_, err = db.SetStock(ctx, 1, nil, 1)
+	require.NoError(t, err)
+
+	reservation, err := db.ReserveStock(ctx, "user1", 1, nil, 1, time.Minute)
+	require.NoError(t, err)
+
+	err = db.ReleaseReservation(ctx, "user2", reservation.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = db.ReleaseReservation(ctx, "user1", reservation.ID)
+	require.NoError(t, err)
+
+	products, _, err := db.GetProducts(ctx, ProductFilter{}, Page{})
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	assert.Equal(t, int64(1), products[0].QuantityAvailable)
+	assert.True(t, products[0].InStock)
+}
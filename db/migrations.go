@@ -38,6 +38,331 @@ var DefaultMigrations = []string{
 		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 		UNIQUE(product_id, review_id)
 	);`,
+
+	// 004 - Add a weighted full-text search vector over product name and description
+	`ALTER TABLE products ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'B')
+	) STORED;`,
+
+	// 005 - Index the search vector for full-text queries
+	`CREATE INDEX products_search_vector_idx ON products USING GIN (search_vector);`,
+
+	// 006 - Enable trigram matching for fuzzy product name suggestions
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm;`,
+
+	// 007 - Index product names for trigram similarity and prefix matching
+	`CREATE INDEX products_name_trgm_idx ON products USING GIN (name gin_trgm_ops);`,
+
+	// 008 - Create categories table, categories form a tree through parent_id
+	`CREATE TABLE categories (
+		id SERIAL PRIMARY KEY,
+		parent_id INTEGER REFERENCES categories(id),
+		name VARCHAR(255) NOT NULL,
+		slug VARCHAR(255) NOT NULL UNIQUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		CHECK (parent_id <> id)
+	);`,
+
+	// 009 - Create product_categories table linking products to categories
+	`CREATE TABLE product_categories (
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+		PRIMARY KEY (product_id, category_id)
+	);`,
+
+	// 010 - Index child category lookups
+	`CREATE INDEX categories_parent_id_idx ON categories (parent_id);`,
+
+	// 011 - Index product lookups by category
+	`CREATE INDEX product_categories_category_id_idx ON product_categories (category_id);`,
+
+	// 012 - Create product_variants table, a NULL price or image falls back to the product's
+	`CREATE TABLE product_variants (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		sku VARCHAR(64) NOT NULL UNIQUE,
+		options JSONB NOT NULL DEFAULT '{}',
+		price DECIMAL(10, 2) CHECK (price >= 0),
+		image TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 013 - Index variant lookups by product
+	`CREATE INDEX product_variants_product_id_idx ON product_variants (product_id);`,
+
+	// 014 - Let reviews optionally reference the variant that was reviewed
+	`ALTER TABLE reviews ADD COLUMN variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL;`,
+
+	// 015 - Create inventory table, stock is tracked per product or per variant
+	`CREATE TABLE inventory (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+		quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 016 - Allow a single stock row per product and variant, NULL variants included
+	`CREATE UNIQUE INDEX inventory_product_variant_idx ON inventory (product_id, (COALESCE(variant_id, 0)));`,
+
+	// 017 - Create stock_reservations table, reservations stop counting once expired
+	`CREATE TABLE stock_reservations (
+		id SERIAL PRIMARY KEY,
+		inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+		user_id VARCHAR(255) NOT NULL,
+		quantity INTEGER NOT NULL CHECK (quantity > 0),
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 018 - Index active reservation lookups
+	`CREATE INDEX stock_reservations_inventory_id_idx ON stock_reservations (inventory_id, expires_at);`,
+
+	// 019 - Create exchange_rates table, rate is units of the currency per unit of the base currency
+	`CREATE TABLE exchange_rates (
+		currency CHAR(3) PRIMARY KEY,
+		rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+		rounding_mode VARCHAR(16) NOT NULL DEFAULT 'half_up',
+		rounding_increment INTEGER NOT NULL DEFAULT 1 CHECK (rounding_increment > 0),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 020 - Create product_prices table, explicit per-currency prices that replace converted ones
+	`CREATE TABLE product_prices (
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		currency CHAR(3) NOT NULL,
+		price NUMERIC(14, 4) NOT NULL CHECK (price >= 0),
+		PRIMARY KEY (product_id, currency)
+	);`,
+
+	// 021 - Create scheduled_price_changes table, a NULL ends_at makes the change permanent
+	`CREATE TABLE scheduled_price_changes (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		price DECIMAL(10, 2) NOT NULL CHECK (price >= 0),
+		starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		ends_at TIMESTAMP WITH TIME ZONE CHECK (ends_at > starts_at),
+		original_price DECIMAL(10, 2),
+		status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'active', 'completed', 'cancelled')),
+		created_by VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 022 - Index the scheduler lookups of due changes
+	`CREATE INDEX scheduled_price_changes_status_idx ON scheduled_price_changes (status, starts_at, ends_at);`,
+
+	// 023 - Create price_history table
+	`CREATE TABLE price_history (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		old_price DECIMAL(10, 2) NOT NULL,
+		new_price DECIMAL(10, 2) NOT NULL,
+		changed_by VARCHAR(255) NOT NULL,
+		scheduled_change_id INTEGER REFERENCES scheduled_price_changes(id) ON DELETE SET NULL,
+		changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 024 - Index price history lookups
+	`CREATE INDEX price_history_product_id_idx ON price_history (product_id, id);`,
+
+	// 025 - Create product_images table for uploaded images
+	`CREATE TABLE product_images (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		key VARCHAR(255) NOT NULL UNIQUE,
+		content_type VARCHAR(64) NOT NULL,
+		size BIGINT NOT NULL CHECK (size > 0),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 026 - Index image lookups by product
+	`CREATE INDEX product_images_product_id_idx ON product_images (product_id, id);`,
+
+	// 027 - Track which images have had their renditions generated
+	`ALTER TABLE product_images ADD COLUMN processed_at TIMESTAMP WITH TIME ZONE;`,
+
+	// 028 - Index the images still waiting for renditions
+	`CREATE INDEX product_images_unprocessed_idx ON product_images (id) WHERE processed_at IS NULL;`,
+
+	// 029 - Create product_image_renditions table for resized copies of images
+	`CREATE TABLE product_image_renditions (
+		id SERIAL PRIMARY KEY,
+		image_id INTEGER NOT NULL REFERENCES product_images(id) ON DELETE CASCADE,
+		width INTEGER NOT NULL CHECK (width > 0),
+		format VARCHAR(8) NOT NULL,
+		key VARCHAR(255) NOT NULL UNIQUE,
+		content_type VARCHAR(64) NOT NULL,
+		size BIGINT NOT NULL CHECK (size > 0),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (image_id, width, format)
+	);`,
+
+	// 030 - Add external_id to products for matching bulk imports
+	`ALTER TABLE products ADD COLUMN external_id VARCHAR(255);`,
+
+	// 031 - External ids are unique among the products that have one
+	`CREATE UNIQUE INDEX products_external_id_idx ON products (external_id) WHERE external_id IS NOT NULL;`,
+
+	// 032 - Archive products instead of deleting them, which would orphan their reviews
+	`ALTER TABLE products ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;`,
+
+	// 033 - Add free-form attributes to products
+	`ALTER TABLE products ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';`,
+
+	// 034 - Index attributes for containment filters
+	`CREATE INDEX products_attributes_idx ON products USING GIN (attributes jsonb_path_ops);`,
+
+	// 035 - Create product_relations table holding precomputed related product scores
+	`CREATE TABLE product_relations (
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		related_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		score DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (product_id, related_id)
+	);`,
+
+	// 036 - Create product_translations table for localized names and descriptions
+	`CREATE TABLE product_translations (
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		locale VARCHAR(35) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (product_id, locale)
+	);`,
+
+	// 037 - Denormalize review aggregates onto products, rating_total is the
+	// sum of the stars and lets the average be maintained incrementally
+	`ALTER TABLE products
+		ADD COLUMN review_count INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN rating_total INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN average_rating NUMERIC(3, 2) NOT NULL DEFAULT 0;`,
+
+	// 038 - Backfill the review aggregates of existing products
+	`UPDATE products p SET
+		review_count = r.count,
+		rating_total = r.total,
+		average_rating = round(r.total::numeric / r.count, 2)
+	FROM (SELECT product_id, count(*) AS count, sum(stars) AS total FROM reviews GROUP BY product_id) r
+	WHERE r.product_id = p.id;`,
+
+	// 039 - Keep the review aggregates of products up to date. Increments
+	// rather than recounts, so concurrent review writes serialize on the
+	// product row instead of missing each other.
+	`CREATE FUNCTION update_product_rating() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP IN ('UPDATE', 'DELETE') THEN
+			UPDATE products SET
+				review_count = review_count - 1,
+				rating_total = rating_total - OLD.stars,
+				average_rating = COALESCE(round((rating_total - OLD.stars)::numeric / NULLIF(review_count - 1, 0), 2), 0)
+			WHERE id = OLD.product_id;
+		END IF;
+		IF TG_OP IN ('INSERT', 'UPDATE') THEN
+			UPDATE products SET
+				review_count = review_count + 1,
+				rating_total = rating_total + NEW.stars,
+				average_rating = round((rating_total + NEW.stars)::numeric / (review_count + 1), 2)
+			WHERE id = NEW.product_id;
+		END IF;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;`,
+
+	// 040 - Run the review aggregates update on every review write
+	`CREATE TRIGGER reviews_update_product_rating
+	AFTER INSERT OR DELETE OR UPDATE OF product_id, stars ON reviews
+	FOR EACH ROW EXECUTE FUNCTION update_product_rating();`,
+
+	// 041 - Index reviews by product for listing and summarizing a product's reviews
+	`CREATE INDEX reviews_product_id_idx ON reviews (product_id, id);`,
+
+	// 042 - Track when reviews were last edited by their author
+	`ALTER TABLE reviews ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;`,
+
+	// 043 - Existing reviews were last written when they were created
+	`UPDATE reviews SET updated_at = created_at WHERE created_at IS NOT NULL;`,
+
+	// 044 - Users may only review a product once. Before the constraint goes
+	// in, archive every review that has a more recent one by the same user of
+	// the same product, along with the review that is kept in its place
+	`CREATE TABLE reviews_deduplicated AS
+	SELECT r.*,
+		(SELECT keep.id FROM reviews keep
+		WHERE keep.user_id = r.user_id AND keep.product_id = r.product_id
+		ORDER BY keep.updated_at DESC, keep.id DESC
+		LIMIT 1) AS kept_review_id,
+		now() AS deduplicated_at
+	FROM reviews r
+	WHERE EXISTS (
+		SELECT 1 FROM reviews newer
+		WHERE newer.user_id = r.user_id AND newer.product_id = r.product_id
+		AND (newer.updated_at, newer.id) > (r.updated_at, r.id)
+	);`,
+
+	// 045 - Archive the product_reviews links of the duplicate reviews too
+	`CREATE TABLE product_reviews_deduplicated AS
+	SELECT pr.* FROM product_reviews pr
+	WHERE pr.review_id IN (SELECT id FROM reviews_deduplicated);`,
+
+	// 046 - Unlink the archived duplicates. This cannot be reversed by a
+	// migration, the rows can only be restored by hand from
+	// product_reviews_deduplicated
+	`DELETE FROM product_reviews WHERE review_id IN (SELECT id FROM reviews_deduplicated);`,
+
+	// 047 - Keep only the most recent review of each user for each product.
+	// This cannot be reversed by a migration, the rows can only be restored
+	// by hand from reviews_deduplicated
+	`DELETE FROM reviews WHERE id IN (SELECT id FROM reviews_deduplicated);`,
+
+	// 048 - One review per user per product
+	`ALTER TABLE reviews ADD CONSTRAINT reviews_user_id_product_id_key UNIQUE (user_id, product_id);`,
+
+	// 049 - Moderate reviews before they are shown, existing reviews were
+	// already public so they start out approved
+	`ALTER TABLE reviews
+		ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'approved' CHECK (status IN ('pending', 'approved', 'rejected')),
+		ADD COLUMN rejection_reason TEXT,
+		ADD COLUMN moderated_by VARCHAR(255),
+		ADD COLUMN moderated_at TIMESTAMP WITH TIME ZONE;`,
+
+	// 050 - Index the moderation queue
+	`CREATE INDEX reviews_status_idx ON reviews (status, id);`,
+
+	// 051 - Only approved reviews count towards the review aggregates of products
+	`CREATE OR REPLACE FUNCTION update_product_rating() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.status = 'approved' THEN
+			UPDATE products SET
+				review_count = review_count - 1,
+				rating_total = rating_total - OLD.stars,
+				average_rating = COALESCE(round((rating_total - OLD.stars)::numeric / NULLIF(review_count - 1, 0), 2), 0)
+			WHERE id = OLD.product_id;
+		END IF;
+		IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.status = 'approved' THEN
+			UPDATE products SET
+				review_count = review_count + 1,
+				rating_total = rating_total + NEW.stars,
+				average_rating = round((rating_total + NEW.stars)::numeric / (review_count + 1), 2)
+			WHERE id = NEW.product_id;
+		END IF;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;`,
+
+	// 052 - Approving or rejecting a review changes the aggregates too
+	`DROP TRIGGER reviews_update_product_rating ON reviews;`,
+
+	// 053 - Run the review aggregates update on status changes as well
+	`CREATE TRIGGER reviews_update_product_rating
+	AFTER INSERT OR DELETE OR UPDATE OF product_id, stars, status ON reviews
+	FOR EACH ROW EXECUTE FUNCTION update_product_rating();`,
+
+	// 054 - Escape text for HTML, search highlights are marked up after escaping
+	`CREATE FUNCTION html_escape(text) RETURNS text AS $$
+		SELECT replace(replace(replace(replace(replace($1,
+			'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;');
+	$$ LANGUAGE sql IMMUTABLE STRICT;`,
 }
 
 // ApplyDefaultMigrations applies the default set of migrations for testing
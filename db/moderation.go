@@ -0,0 +1,120 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Statuses a review moves through. New reviews start out pending or
+// approved depending on configuration, only approved reviews are shown
+// publicly and counted in ratings. Editing a review puts it back to the
+// status new reviews start out in.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// ModeratedReview is a review as seen by the admins moderating it
+type ModeratedReview struct {
+	ID            int64     `db:"id" json:"id"`
+	UserId        string    `db:"user_id" json:"userId"`
+	ProductID     int64     `db:"product_id" json:"productId"`
+	VariantID     *int64    `db:"variant_id" json:"variantId,omitempty"`
+	ReviewTitle   string    `db:"review_title" json:"reviewTitle"`
+	ReviewContent string    `db:"review_content" json:"reviewContent"`
+	Stars         float64   `db:"stars" json:"stars"`
+	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updatedAt"`
+	Status        string    `db:"status" json:"status"`
+	// RejectionReason is only set on rejected reviews
+	RejectionReason *string    `db:"rejection_reason" json:"rejectionReason,omitempty"`
+	ModeratedBy     *string    `db:"moderated_by" json:"moderatedBy,omitempty"`
+	ModeratedAt     *time.Time `db:"moderated_at" json:"moderatedAt,omitempty"`
+}
+
+const moderatedReviewColumns = reviewColumns + ", status, rejection_reason, moderated_by, moderated_at"
+
+// GetReviewsByStatus returns one page of the reviews in a status, oldest
+// first, along with the cursor of the next page. Listing the pending
+// reviews gives the moderation queue.
+func (db *DB) GetReviewsByStatus(ctx context.Context, status string, page Page) ([]ModeratedReview, string, error) {
+	c, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit := page.limit()
+
+	rows, err := db.pool.Query(ctx, `
+	SELECT `+moderatedReviewColumns+` FROM reviews WHERE status = $1 AND id > $2 ORDER BY id LIMIT $3
+	`, status, c.ID, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query reviews: %w", err)
+	}
+	reviews, err := pgx.CollectRows(rows, pgx.RowToStructByName[ModeratedReview])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query reviews: %w", err)
+	}
+
+	var next string
+	if len(reviews) > limit {
+		reviews = reviews[:limit]
+		next = encodeCursor(cursor{ID: reviews[limit-1].ID})
+	}
+	return reviews, next, nil
+}
+
+// ModerateReviews approves or rejects reviews on behalf of an admin. The
+// reason is kept on rejected reviews and cleared on approved ones. Either
+// every review is moderated or, when one of them does not exist, none is.
+func (db *DB) ModerateReviews(ctx context.Context, ids []int64, status, reason, moderatorId string) ([]ModeratedReview, error) {
+	if status != ReviewApproved && status != ReviewRejected {
+		return nil, fmt.Errorf("reviews can only be moderated to %s or %s, not %q", ReviewApproved, ReviewRejected, status)
+	}
+	var rejectionReason *string
+	if status == ReviewRejected {
+		rejectionReason = &reason
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+	UPDATE reviews SET status = $2, rejection_reason = $3, moderated_by = $4, moderated_at = now()
+	WHERE id = ANY($1)
+	RETURNING `+moderatedReviewColumns,
+		ids, status, rejectionReason, moderatorId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to moderate reviews: %w", err)
+	}
+	reviews, err := pgx.CollectRows(rows, pgx.RowToStructByName[ModeratedReview])
+	if err != nil {
+		return nil, fmt.Errorf("failed to moderate reviews: %w", err)
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(reviews, func(r ModeratedReview) bool { return r.ID == id }) {
+			return nil, fmt.Errorf("review with id %d %w", id, ErrNotFound)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit moderation: %w", err)
+	}
+
+	productIds := make([]int64, len(reviews))
+	for i, review := range reviews {
+		productIds[i] = review.ProductID
+	}
+	db.ratingSummaries.invalidate(productIds...)
+
+	slices.SortFunc(reviews, func(a, b ModeratedReview) int { return cmp.Compare(a.ID, b.ID) })
+	return reviews, nil
+}
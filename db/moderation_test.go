@@ -0,0 +1,98 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModerateReviews(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	var ids []int64
+	for i, user := range []string{"1", "2", "3"} {
+		review, err := db.PostReview(ctx, ClientReview{ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: float64(i + 3)}, user, ReviewPending)
+		require.NoError(t, err)
+		ids = append(ids, review.ID)
+	}
+
+	// Pending reviews are neither listed nor counted
+	reviews, _, err := db.GetProductReviews(ctx, 1, Page{})
+	require.NoError(t, err)
+	assert.Empty(t, reviews)
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), product.ReviewCount)
+	prior := RatingPrior{Mean: 3, Weight: 2}
+	summary, err := db.GetRatingSummary(ctx, 1, prior)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), summary.Count)
+
+	queue, next, err := db.GetReviewsByStatus(ctx, ReviewPending, Page{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, queue, 2)
+	require.NotEmpty(t, next)
+	assert.Equal(t, ids[0], queue[0].ID)
+	assert.Equal(t, "1", queue[0].UserId)
+	assert.Equal(t, ReviewPending, queue[0].Status)
+
+	approved, err := db.ModerateReviews(ctx, ids[:2], ReviewApproved, "", "admin1")
+	require.NoError(t, err)
+	require.Len(t, approved, 2)
+	assert.Equal(t, ReviewApproved, approved[0].Status)
+	require.NotNil(t, approved[0].ModeratedBy)
+	assert.Equal(t, "admin1", *approved[0].ModeratedBy)
+	assert.NotNil(t, approved[0].ModeratedAt)
+
+	rejected, err := db.ModerateReviews(ctx, ids[2:], ReviewRejected, "Spam", "admin1")
+	require.NoError(t, err)
+	require.NotNil(t, rejected[0].RejectionReason)
+	assert.Equal(t, "Spam", *rejected[0].RejectionReason)
+
+	reviews, _, err = db.GetProductReviews(ctx, 1, Page{})
+	require.NoError(t, err)
+	require.Len(t, reviews, 2)
+	product, err = db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), product.ReviewCount)
+	assert.Equal(t, 3.5, product.AverageRating)
+	summary, err = db.GetRatingSummary(ctx, 1, prior)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), summary.Count)
+
+	// Rejecting an approved review takes it out of the aggregates again
+	_, err = db.ModerateReviews(ctx, ids[1:2], ReviewRejected, "Off topic", "admin1")
+	require.NoError(t, err)
+	product, err = db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), product.ReviewCount)
+	assert.Equal(t, 3.0, product.AverageRating)
+
+	// Editing a review sends it back to moderation and clears the rejection
+	edited, err := db.UpdateReview(ctx, ids[2], "3", ReviewEdit{ReviewTitle: "Title", ReviewContent: "Better content", Stars: 5}, ReviewPending)
+	require.NoError(t, err)
+	queue, _, err = db.GetReviewsByStatus(ctx, ReviewPending, Page{})
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.Equal(t, edited.ID, queue[0].ID)
+	assert.Nil(t, queue[0].RejectionReason)
+
+	// A batch with a missing review moderates nothing
+	_, err = db.ModerateReviews(ctx, []int64{ids[2], 42}, ReviewApproved, "", "admin1")
+	require.ErrorIs(t, err, ErrNotFound)
+	queue, _, err = db.GetReviewsByStatus(ctx, ReviewPending, Page{})
+	require.NoError(t, err)
+	assert.Len(t, queue, 1)
+
+	_, err = db.ModerateReviews(ctx, ids[:1], ReviewPending, "", "admin1")
+	require.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// DefaultPageLimit is used when a Page does not specify a limit
+	DefaultPageLimit = 20
+	// MaxPageLimit caps how many rows a single page can return
+	MaxPageLimit = 100
+)
+
+// Page describes which slice of a keyset-paginated collection to return.
+// Cursor is the opaque value handed out as the previous page's next cursor.
+type Page struct {
+	Limit  int
+	Cursor string
+}
+
+// limit returns the effective page size
+func (p Page) limit() int {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+// cursor is the decoded form of a page cursor, it records the last row seen.
+// Sorted listings also record the ordering and the row's value for each sort key.
+type cursor struct {
+	ID     int64    `json:"id"`
+	Sort   string   `json:"sort,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	if s == "" {
+		return c, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c, err := decodeCursor(encodeCursor(cursor{ID: 42}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), c.ID)
+
+	c, err = decodeCursor("")
+	require.NoError(t, err)
+	assert.Zero(t, c.ID)
+
+	_, err = decodeCursor("!!!")
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestPageLimit(t *testing.T) {
+	assert.Equal(t, DefaultPageLimit, Page{}.limit())
+	assert.Equal(t, 5, Page{Limit: 5}.limit())
+	assert.Equal(t, MaxPageLimit, Page{Limit: MaxPageLimit + 1}.limit())
+}
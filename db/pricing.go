@@ -0,0 +1,354 @@
+package db
+
+import (
+	"catalogapi/money"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Statuses a scheduled price change moves through. A pending change becomes
+// active when it starts, and completed when it ends or right away when it
+// has no end. Cancelled changes are never applied again.
+const (
+	PriceChangePending   = "pending"
+	PriceChangeActive    = "active"
+	PriceChangeCompleted = "completed"
+	PriceChangeCancelled = "cancelled"
+)
+
+// PriceChange is an entry of a product's price history
+type PriceChange struct {
+	ID        int64       `db:"id" json:"id"`
+	ProductID int64       `db:"product_id" json:"product_id"`
+	OldPrice  money.Money `db:"old_price" json:"old_price"`
+	NewPrice  money.Money `db:"new_price" json:"new_price"`
+	ChangedBy string      `db:"changed_by" json:"changed_by"`
+	// ScheduledChangeID is set when the change was applied by the scheduler
+	ScheduledChangeID *int64    `db:"scheduled_change_id" json:"scheduled_change_id"`
+	ChangedAt         time.Time `db:"changed_at" json:"changed_at"`
+}
+
+// ScheduledPriceChange sets a product's price at StartsAt. When EndsAt is
+// set the price in place before the change is restored at that time.
+type ScheduledPriceChange struct {
+	ID            int64        `db:"id" json:"id"`
+	ProductID     int64        `db:"product_id" json:"product_id"`
+	Price         money.Money  `db:"price" json:"price"`
+	StartsAt      time.Time    `db:"starts_at" json:"starts_at"`
+	EndsAt        *time.Time   `db:"ends_at" json:"ends_at"`
+	OriginalPrice *money.Money `db:"original_price" json:"original_price"`
+	Status        string       `db:"status" json:"status"`
+	CreatedBy     string       `db:"created_by" json:"created_by"`
+	CreatedAt     time.Time    `db:"created_at" json:"created_at"`
+}
+
+// ClientScheduledPriceChange is the payload accepted when scheduling a price change
+type ClientScheduledPriceChange struct {
+	Price    money.Money `json:"price"`
+	StartsAt time.Time   `json:"starts_at"`
+	EndsAt   *time.Time  `json:"ends_at"`
+}
+
+const (
+	priceChangeColumns          = "id, product_id, old_price, new_price, changed_by, scheduled_change_id, changed_at"
+	scheduledPriceChangeColumns = "id, product_id, price, starts_at, ends_at, original_price, status, created_by, created_at"
+)
+
+// GetPriceHistory returns one page of a product's price changes, newest
+// first, along with the cursor of the next page
+func (db *DB) GetPriceHistory(ctx context.Context, productID int64, page Page) ([]PriceChange, string, error) {
+	c, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit := page.limit()
+
+	var exists bool
+	err = db.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query product: %w", err)
+	}
+	if !exists {
+		return nil, "", fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+
+	rows, err := db.pool.Query(ctx,
+		"SELECT "+priceChangeColumns+` FROM price_history
+		WHERE product_id = $1 AND ($2::integer = 0 OR id < $2)
+		ORDER BY id DESC LIMIT $3`,
+		productID, c.ID, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query price history: %w", err)
+	}
+	changes, err := pgx.CollectRows(rows, pgx.RowToStructByName[PriceChange])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query price history: %w", err)
+	}
+
+	var next string
+	if len(changes) > limit {
+		changes = changes[:limit]
+		next = encodeCursor(cursor{ID: changes[limit-1].ID})
+	}
+	return changes, next, nil
+}
+
+// GetScheduledPriceChanges returns every scheduled price change of a product ordered by start
+func (db *DB) GetScheduledPriceChanges(ctx context.Context, productID int64) ([]ScheduledPriceChange, error) {
+	rows, err := db.pool.Query(ctx,
+		"SELECT "+scheduledPriceChangeColumns+" FROM scheduled_price_changes WHERE product_id = $1 ORDER BY starts_at, id",
+		productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled price changes: %w", err)
+	}
+	changes, err := pgx.CollectRows(rows, pgx.RowToStructByName[ScheduledPriceChange])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled price changes: %w", err)
+	}
+	return changes, nil
+}
+
+// CreateScheduledPriceChange schedules a price change made by userID. It is
+// rejected with ErrConflict when it overlaps another pending or active change
+// of the product, since ending one would undo the other.
+func (db *DB) CreateScheduledPriceChange(ctx context.Context, productID int64, c ClientScheduledPriceChange, userID string) (ScheduledPriceChange, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return ScheduledPriceChange{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the product serializes concurrent schedules for it
+	var id int64
+	err = tx.QueryRow(ctx, "SELECT id FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ScheduledPriceChange{}, fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+	if err != nil {
+		return ScheduledPriceChange{}, fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	var overlapping int64
+	err = tx.QueryRow(ctx,
+		`SELECT id FROM scheduled_price_changes
+		WHERE product_id = $1 AND status IN ('pending', 'active')
+			AND tstzrange(starts_at, COALESCE(ends_at, starts_at), '[]') && tstzrange($2, COALESCE($3, $2), '[]')
+		LIMIT 1`,
+		productID, c.StartsAt, c.EndsAt).Scan(&overlapping)
+	if err == nil {
+		return ScheduledPriceChange{}, fmt.Errorf("%w: overlaps scheduled price change %d", ErrConflict, overlapping)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return ScheduledPriceChange{}, fmt.Errorf("failed to query scheduled price changes: %w", err)
+	}
+
+	rows, err := tx.Query(ctx,
+		`INSERT INTO scheduled_price_changes (product_id, price, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+scheduledPriceChangeColumns,
+		productID, c.Price, c.StartsAt, c.EndsAt, userID)
+	if err != nil {
+		return ScheduledPriceChange{}, fmt.Errorf("failed to insert scheduled price change: %w", err)
+	}
+	change, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ScheduledPriceChange])
+	if err != nil {
+		return ScheduledPriceChange{}, fmt.Errorf("failed to insert scheduled price change: %w", translateError(err))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ScheduledPriceChange{}, fmt.Errorf("failed to commit scheduled price change: %w", err)
+	}
+	return change, nil
+}
+
+// CancelScheduledPriceChange cancels a pending change, or ends an active one
+// early by restoring the price it replaced
+func (db *DB) CancelScheduledPriceChange(ctx context.Context, productID, id int64, userID string) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		"SELECT "+scheduledPriceChangeColumns+" FROM scheduled_price_changes WHERE product_id = $1 AND id = $2 FOR UPDATE",
+		productID, id)
+	if err != nil {
+		return fmt.Errorf("failed to query scheduled price change: %w", err)
+	}
+	change, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ScheduledPriceChange])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("scheduled price change with id %d of product %d %w", id, productID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query scheduled price change: %w", err)
+	}
+
+	switch change.Status {
+	case PriceChangePending:
+		err = setScheduledPriceChangeStatus(ctx, tx, change.ID, PriceChangeCancelled)
+	case PriceChangeActive:
+		err = endScheduledPriceChange(ctx, tx, change, userID, PriceChangeCancelled)
+	default:
+		return fmt.Errorf("%w: scheduled price change %d is already %s", ErrConflict, id, change.Status)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit scheduled price change: %w", err)
+	}
+	return nil
+}
+
+// ApplyScheduledPriceChanges starts and ends every scheduled price change
+// that is due and returns how many transitions were applied. Each one runs
+// in its own transaction and skips rows locked by another instance, so
+// several servers can run the scheduler side by side. A change that fails
+// is left as it is for the next run and skipped for the rest of this one,
+// so it does not hold up the changes due after it. The failures are
+// returned together once every other due change has been applied.
+func (db *DB) ApplyScheduledPriceChanges(ctx context.Context) (int, error) {
+	applied := 0
+	failed := []int64{}
+	var errs []error
+	for {
+		id, err := db.applyNextScheduledPriceChange(ctx, failed)
+		if err != nil && id != 0 {
+			failed = append(failed, id)
+			errs = append(errs, fmt.Errorf("scheduled price change %d: %w", id, err))
+			continue
+		}
+		if err != nil {
+			return applied, errors.Join(append(errs, err)...)
+		}
+		if id == 0 {
+			return applied, errors.Join(errs...)
+		}
+		applied++
+	}
+}
+
+// applyNextScheduledPriceChange applies the first due change whose id is
+// not in skip and returns its id, or 0 when no change is due. An error with
+// a zero id means no change could be picked at all.
+func (db *DB) applyNextScheduledPriceChange(ctx context.Context, skip []int64) (int64, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		"SELECT "+scheduledPriceChangeColumns+` FROM scheduled_price_changes
+		WHERE ((status = 'pending' AND starts_at <= now()) OR (status = 'active' AND ends_at <= now()))
+		AND id <> ALL($1)
+		ORDER BY CASE WHEN status = 'pending' THEN starts_at ELSE ends_at END, id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, skip)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query due price changes: %w", err)
+	}
+	change, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ScheduledPriceChange])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query due price changes: %w", err)
+	}
+
+	if change.Status == PriceChangePending {
+		err = startScheduledPriceChange(ctx, tx, change)
+	} else {
+		err = endScheduledPriceChange(ctx, tx, change, change.CreatedBy, PriceChangeCompleted)
+	}
+	if err != nil {
+		return change.ID, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return change.ID, fmt.Errorf("failed to commit price change: %w", err)
+	}
+	return change.ID, nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// recordPriceChange adds a price history entry, unchanged prices are not recorded
+func recordPriceChange(ctx context.Context, tx pgx.Tx, productID int64, oldPrice, newPrice money.Money, changedBy string, scheduledChangeID *int64) error {
+	if oldPrice.Equal(newPrice) {
+		return nil
+	}
+	_, err := tx.Exec(ctx,
+		`INSERT INTO price_history (product_id, old_price, new_price, changed_by, scheduled_change_id)
+		VALUES ($1, $2, $3, $4, $5)`,
+		productID, oldPrice, newPrice, changedBy, scheduledChangeID)
+	if err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
+
+// startScheduledPriceChange puts the scheduled price in place and remembers
+// the price it replaced
+func startScheduledPriceChange(ctx context.Context, tx pgx.Tx, change ScheduledPriceChange) error {
+	var current money.Money
+	err := tx.QueryRow(ctx, "SELECT price FROM products WHERE id = $1 FOR UPDATE", change.ProductID).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE products SET price = $2 WHERE id = $1", change.ProductID, change.Price); err != nil {
+		return fmt.Errorf("failed to update product price: %w", err)
+	}
+	if err := recordPriceChange(ctx, tx, change.ProductID, current, change.Price, change.CreatedBy, &change.ID); err != nil {
+		return err
+	}
+
+	status := PriceChangeActive
+	if change.EndsAt == nil {
+		status = PriceChangeCompleted
+	}
+	_, err = tx.Exec(ctx,
+		"UPDATE scheduled_price_changes SET status = $2, original_price = $3 WHERE id = $1",
+		change.ID, status, current)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled price change: %w", err)
+	}
+	return nil
+}
+
+// endScheduledPriceChange restores the price replaced by an active change.
+// If the price was edited by hand while the change was active, the edit
+// wins and the price is left alone.
+func endScheduledPriceChange(ctx context.Context, tx pgx.Tx, change ScheduledPriceChange, changedBy, status string) error {
+	var current money.Money
+	err := tx.QueryRow(ctx, "SELECT price FROM products WHERE id = $1 FOR UPDATE", change.ProductID).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	if current.Equal(change.Price) && change.OriginalPrice != nil {
+		if _, err := tx.Exec(ctx, "UPDATE products SET price = $2 WHERE id = $1", change.ProductID, *change.OriginalPrice); err != nil {
+			return fmt.Errorf("failed to update product price: %w", err)
+		}
+		if err := recordPriceChange(ctx, tx, change.ProductID, current, *change.OriginalPrice, changedBy, &change.ID); err != nil {
+			return err
+		}
+	}
+	return setScheduledPriceChangeStatus(ctx, tx, change.ID, status)
+}
+
+func setScheduledPriceChangeStatus(ctx context.Context, tx pgx.Tx, id int64, status string) error {
+	if _, err := tx.Exec(ctx, "UPDATE scheduled_price_changes SET status = $2 WHERE id = $1", id, status); err != nil {
+		return fmt.Errorf("failed to update scheduled price change: %w", err)
+	}
+	return nil
+}
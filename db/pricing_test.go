@@ -0,0 +1,300 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceHistory(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	price := money.MustParse("17.99", "USD")
+	_, err = db.PatchProduct(ctx, 1, ProductPatch{Price: &price}, "admin1")
+	require.NoError(t, err)
+
+	// Changes that leave the price alone are not recorded
+	name := "Plain T-Shirt"
+	_, err = db.PatchProduct(ctx, 1, ProductPatch{Name: &name}, "admin1")
+	require.NoError(t, err)
+
+	_, err = db.UpdateProduct(ctx, 1, ClientProduct{Name: name, Price: money.MustParse("21.00", "USD"), Image: "https://via.placeholder.com/150"}, "admin2")
+	require.NoError(t, err)
+
+	changes, next, err := db.GetPriceHistory(ctx, 1, Page{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, money.MustParse("17.99", "USD"), changes[0].OldPrice)
+	assert.Equal(t, money.MustParse("21.00", "USD"), changes[0].NewPrice)
+	assert.Equal(t, "admin2", changes[0].ChangedBy)
+	assert.Nil(t, changes[0].ScheduledChangeID)
+	require.NotEmpty(t, next)
+
+	changes, next, err = db.GetPriceHistory(ctx, 1, Page{Limit: 1, Cursor: next})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, money.MustParse("19.99", "USD"), changes[0].OldPrice)
+	assert.Equal(t, money.MustParse("17.99", "USD"), changes[0].NewPrice)
+	assert.Equal(t, "admin1", changes[0].ChangedBy)
+	assert.Empty(t, next)
+
+	_, _, err = db.GetPriceHistory(ctx, 42, Page{})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCreateScheduledPriceChange(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	end := start.Add(48 * time.Hour)
+	change, err := db.CreateScheduledPriceChange(ctx, 1, ClientScheduledPriceChange{
+		Price:    money.MustParse("14.99", "USD"),
+		StartsAt: start,
+		EndsAt:   &end,
+	}, "admin1")
+	require.NoError(t, err)
+	assert.Equal(t, PriceChangePending, change.Status)
+	assert.Equal(t, "admin1", change.CreatedBy)
+	assert.Nil(t, change.OriginalPrice)
+
+	// A permanent change in the middle of the sale would be undone when it ends
+	_, err = db.CreateScheduledPriceChange(ctx, 1, ClientScheduledPriceChange{
+		Price:    money.MustParse("24.99", "USD"),
+		StartsAt: start.Add(time.Hour),
+	}, "admin1")
+	require.ErrorIs(t, err, ErrConflict)
+
+	after := end.Add(time.Hour)
+	_, err = db.CreateScheduledPriceChange(ctx, 1, ClientScheduledPriceChange{
+		Price:    money.MustParse("24.99", "USD"),
+		StartsAt: after,
+	}, "admin1")
+	require.NoError(t, err)
+
+	changes, err := db.GetScheduledPriceChanges(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, change.ID, changes[0].ID)
+
+	_, err = db.CreateScheduledPriceChange(ctx, 42, ClientScheduledPriceChange{Price: money.MustParse("1", "USD"), StartsAt: start}, "admin1")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestApplyScheduledPriceChanges(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	end := time.Now().Add(time.Hour)
+	sale, err := db.CreateScheduledPriceChange(ctx, 1, ClientScheduledPriceChange{
+		Price:    money.MustParse("14.99", "USD"),
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   &end,
+	}, "admin1")
+	require.NoError(t, err)
+	_, err = db.CreateScheduledPriceChange(ctx, 2, ClientScheduledPriceChange{
+		Price:    money.MustParse("44.99", "USD"),
+		StartsAt: time.Now().Add(-time.Minute),
+	}, "admin1")
+	require.NoError(t, err)
+	_, err = db.CreateScheduledPriceChange(ctx, 2, ClientScheduledPriceChange{
+		Price:    money.MustParse("39.99", "USD"),
+		StartsAt: time.Now().Add(time.Hour),
+	}, "admin1")
+	require.NoError(t, err)
+
+	applied, err := db.ApplyScheduledPriceChanges(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("14.99", "USD"), product.Price)
+	product, err = db.GetProduct(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("44.99", "USD"), product.Price)
+
+	changes, err := db.GetScheduledPriceChanges(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, PriceChangeCompleted, changes[0].Status)
+	assert.Equal(t, PriceChangePending, changes[1].Status)
+
+	// Nothing else is due yet
+	applied, err = db.ApplyScheduledPriceChanges(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	_, err = db.pool.Exec(ctx, "UPDATE scheduled_price_changes SET ends_at = now() - interval '1 second' WHERE id = $1", sale.ID)
+	require.NoError(t, err)
+	applied, err = db.ApplyScheduledPriceChanges(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+
+	product, err = db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("19.99", "USD"), product.Price)
+
+	history, _, err := db.GetPriceHistory(ctx, 1, Page{})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, money.MustParse("19.99", "USD"), history[0].NewPrice)
+	require.NotNil(t, history[0].ScheduledChangeID)
+	assert.Equal(t, sale.ID, *history[0].ScheduledChangeID)
+	assert.Equal(t, "admin1", history[0].ChangedBy)
+}
+
+func TestApplyScheduledPriceChangesSkipsFailures(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	// Every price update of the first product fails
+	_, err = db.pool.Exec(ctx, `CREATE FUNCTION fail_price_update() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'price of product % is frozen', NEW.id;
+	END;
+	$$ LANGUAGE plpgsql`)
+	require.NoError(t, err)
+	_, err = db.pool.Exec(ctx, `CREATE TRIGGER products_fail_price_update BEFORE UPDATE OF price ON products
+	FOR EACH ROW WHEN (NEW.id = 1) EXECUTE FUNCTION fail_price_update()`)
+	require.NoError(t, err)
+
+	frozen, err := db.CreateScheduledPriceChange(ctx, 1, ClientScheduledPriceChange{
+		Price:    money.MustParse("14.99", "USD"),
+		StartsAt: time.Now().Add(-2 * time.Minute),
+	}, "admin1")
+	require.NoError(t, err)
+	_, err = db.CreateScheduledPriceChange(ctx, 2, ClientScheduledPriceChange{
+		Price:    money.MustParse("44.99", "USD"),
+		StartsAt: time.Now().Add(-time.Minute),
+	}, "admin1")
+	require.NoError(t, err)
+
+	applied, err := db.ApplyScheduledPriceChanges(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "frozen")
+	assert.Equal(t, 1, applied)
+
+	product, err := db.GetProduct(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("44.99", "USD"), product.Price)
+
+	// The failed change is left pending for the next run
+	changes, err := db.GetScheduledPriceChanges(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, frozen.ID, changes[0].ID)
+	assert.Equal(t, PriceChangePending, changes[0].Status)
+
+	applied, err = db.ApplyScheduledPriceChanges(ctx)
+	require.Error(t, err)
+	assert.Equal(t, 0, applied)
+}
+
+func TestScheduledPriceChangeManualEdit(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	end := time.Now().Add(time.Hour)
+	sale, err := db.CreateScheduledPriceChange(ctx, 1, ClientScheduledPriceChange{
+		Price:    money.MustParse("14.99", "USD"),
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   &end,
+	}, "admin1")
+	require.NoError(t, err)
+	_, err = db.ApplyScheduledPriceChanges(ctx)
+	require.NoError(t, err)
+
+	// A price edited by hand during the sale is kept when the sale ends
+	price := money.MustParse("12.99", "USD")
+	_, err = db.PatchProduct(ctx, 1, ProductPatch{Price: &price}, "admin2")
+	require.NoError(t, err)
+
+	err = db.CancelScheduledPriceChange(ctx, 1, sale.ID, "admin2")
+	require.NoError(t, err)
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, price, product.Price)
+
+	err = db.CancelScheduledPriceChange(ctx, 1, sale.ID, "admin2")
+	require.ErrorIs(t, err, ErrConflict)
+
+	err = db.CancelScheduledPriceChange(ctx, 1, 42, "admin2")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCancelActiveScheduledPriceChange(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	end := time.Now().Add(time.Hour)
+	sale, err := db.CreateScheduledPriceChange(ctx, 1, ClientScheduledPriceChange{
+		Price:    money.MustParse("14.99", "USD"),
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   &end,
+	}, "admin1")
+	require.NoError(t, err)
+	_, err = db.ApplyScheduledPriceChanges(ctx)
+	require.NoError(t, err)
+
+	err = db.CancelScheduledPriceChange(ctx, 1, sale.ID, "admin2")
+	require.NoError(t, err)
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("19.99", "USD"), product.Price)
+
+	changes, err := db.GetScheduledPriceChanges(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, PriceChangeCancelled, changes[0].Status)
+	require.NotNil(t, changes[0].OriginalPrice)
+	assert.Equal(t, money.MustParse("19.99", "USD"), *changes[0].OriginalPrice)
+
+	history, _, err := db.GetPriceHistory(ctx, 1, Page{})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "admin2", history[0].ChangedBy)
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"catalogapi/money"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProductFilter narrows down and orders a product listing, zero fields are not applied
+type ProductFilter struct {
+	MinPrice     *money.Money
+	MaxPrice     *money.Money
+	Query        string
+	CreatedAfter *time.Time
+	// CategoryID limits the listing to a category and all of its descendants
+	CategoryID *int64
+	// Attributes keeps the products that have, for every listed attribute,
+	// one of the listed values
+	Attributes map[string][]string
+	// IncludeArchived lists archived products along with the live ones
+	IncludeArchived bool
+	Sort            []SortField
+}
+
+// SortField orders a listing by one of the whitelisted sort fields
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// productSortColumn maps a public sort field onto its SQL expression and
+// knows how to read the value of that field for a cursor
+type productSortColumn struct {
+	expr  string
+	value func(Product) string
+}
+
+var productSortColumns = map[string]productSortColumn{
+	"id": {
+		expr:  "id",
+		value: func(p Product) string { return strconv.FormatInt(p.ID, 10) },
+	},
+	"name": {
+		expr:  "name",
+		value: func(p Product) string { return p.Name },
+	},
+	"price": {
+		expr:  "price",
+		value: func(p Product) string { return p.Price.Decimal() },
+	},
+	"created_at": {
+		expr:  "created_at",
+		value: func(p Product) string { return p.CreatedAt.Format(time.RFC3339Nano) },
+	},
+	"rating": {
+		expr:  "average_rating",
+		value: func(p Product) string { return strconv.FormatFloat(p.AverageRating, 'f', 2, 64) },
+	},
+}
+
+// ProductSortFields returns the field names products can be sorted by
+func ProductSortFields() []string {
+	fields := make([]string, 0, len(productSortColumns))
+	for field := range productSortColumns {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// queryBuilder accumulates WHERE conditions and their positional arguments
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// arg registers a query argument and returns its placeholder
+func (q *queryBuilder) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *queryBuilder) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+func (q *queryBuilder) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// orderKeys returns the filter sort with id appended as a tie-breaker so
+// that the ordering is total and usable for keyset pagination
+func (f ProductFilter) orderKeys() ([]SortField, error) {
+	keys := make([]SortField, 0, len(f.Sort)+1)
+	seen := make(map[string]bool, len(f.Sort))
+	for _, s := range f.Sort {
+		if _, ok := productSortColumns[s.Field]; !ok {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidFilter, s.Field)
+		}
+		if seen[s.Field] {
+			return nil, fmt.Errorf("%w: duplicate sort field %q", ErrInvalidFilter, s.Field)
+		}
+		seen[s.Field] = true
+		keys = append(keys, s)
+	}
+	if !seen["id"] {
+		keys = append(keys, SortField{Field: "id"})
+	}
+	return keys, nil
+}
+
+// sortSignature identifies an ordering so cursors cannot be replayed against another one
+func sortSignature(keys []SortField) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// productCursor builds the cursor pointing just after p for the given ordering
+func productCursor(p Product, keys []SortField) cursor {
+	c := cursor{ID: p.ID, Sort: sortSignature(keys)}
+	for _, k := range keys {
+		c.Values = append(c.Values, productSortColumns[k.Field].value(p))
+	}
+	return c
+}
+
+// buildProductsQuery turns a validated filter, a decoded cursor and a row
+// limit into a parameterized SELECT over the products table
+func buildProductsQuery(f ProductFilter, c cursor, limit int) (string, []any, error) {
+	keys, err := f.orderKeys()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var q queryBuilder
+	q.whereFilter(f, "")
+	if err := q.whereAfter(keys, c); err != nil {
+		return "", nil, err
+	}
+
+	order := make([]string, len(keys))
+	for i, k := range keys {
+		order[i] = productSortColumns[k.Field].expr
+		if k.Desc {
+			order[i] += " DESC"
+		}
+	}
+
+	sql := "SELECT " + productColumns + " FROM products" + q.whereClause() +
+		" ORDER BY " + strings.Join(order, ", ") +
+		" LIMIT " + q.arg(limit)
+	return sql, q.args, nil
+}
+
+// whereFilter adds the conditions of a product filter. When facetKey is set
+// to the SQL expression of an attribute name, the attribute filter matching
+// that name is waived, which lets a facet count its other values.
+func (q *queryBuilder) whereFilter(f ProductFilter, facetKey string) {
+	if !f.IncludeArchived {
+		q.where("archived_at IS NULL")
+	}
+	if f.MinPrice != nil {
+		q.where("price >= " + q.arg(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		q.where("price <= " + q.arg(*f.MaxPrice))
+	}
+	if f.Query != "" {
+		q.where("name ILIKE '%' || " + q.arg(escapeLike(f.Query)) + " || '%'")
+	}
+	if f.CreatedAfter != nil {
+		q.where("created_at > " + q.arg(*f.CreatedAfter))
+	}
+	if f.CategoryID != nil {
+		q.where("id IN (WITH RECURSIVE " + fmt.Sprintf(descendantCategoriesCTE, q.arg(*f.CategoryID)) +
+			" SELECT pc.product_id FROM product_categories pc JOIN subtree s ON pc.category_id = s.id)")
+	}
+
+	// Sorted so that the same filter always builds the same query
+	names := slices.Sorted(maps.Keys(f.Attributes))
+	for _, name := range names {
+		var matches []string
+		if facetKey != "" {
+			matches = append(matches, facetKey+" = "+q.arg(name))
+		}
+		// Containment keeps the lookup on the GIN index of the column
+		for _, value := range f.Attributes[name] {
+			matches = append(matches, "attributes @> "+q.arg(map[string]string{name: value}))
+		}
+		condition := strings.Join(matches, " OR ")
+		if len(matches) > 1 {
+			condition = "(" + condition + ")"
+		}
+		q.where(condition)
+	}
+}
+
+// whereAfter adds the keyset condition that skips every row up to and
+// including the cursor row. For keys (a, b) it produces
+// (a > $1) OR (a = $1 AND b > $2), flipping the operator for DESC keys.
+func (q *queryBuilder) whereAfter(keys []SortField, c cursor) error {
+	if c.ID == 0 && c.Sort == "" {
+		return nil
+	}
+	if c.Sort != sortSignature(keys) || len(c.Values) != len(keys) {
+		return fmt.Errorf("%w: cursor does not match the requested sort", ErrInvalidCursor)
+	}
+
+	placeholders := make([]string, len(keys))
+	for i, v := range c.Values {
+		placeholders[i] = q.arg(v)
+	}
+
+	branches := make([]string, len(keys))
+	for i, k := range keys {
+		var parts []string
+		for j := range i {
+			parts = append(parts, productSortColumns[keys[j].Field].expr+" = "+placeholders[j])
+		}
+		op := " > "
+		if k.Desc {
+			op = " < "
+		}
+		parts = append(parts, productSortColumns[k.Field].expr+op+placeholders[i])
+		branches[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	q.where("(" + strings.Join(branches, " OR ") + ")")
+	return nil
+}
+
+// escapeLike escapes the LIKE wildcards so user input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
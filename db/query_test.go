@@ -0,0 +1,104 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildProductsQuery(t *testing.T) {
+	minPrice := money.MustParse("10", "USD")
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		filter       ProductFilter
+		cursor       cursor
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			name:         "no filter",
+			expectedSQL:  "SELECT id, name, price, image, description, attributes, average_rating, review_count, external_id, archived_at, created_at FROM products WHERE archived_at IS NULL ORDER BY id LIMIT $1",
+			expectedArgs: []any{21},
+		},
+		{
+			name:         "filters",
+			filter:       ProductFilter{MinPrice: &minPrice, Query: "50%_off", CreatedAfter: &createdAfter},
+			expectedSQL:  "SELECT id, name, price, image, description, attributes, average_rating, review_count, external_id, archived_at, created_at FROM products WHERE archived_at IS NULL AND price >= $1 AND name ILIKE '%' || $2 || '%' AND created_at > $3 ORDER BY id LIMIT $4",
+			expectedArgs: []any{minPrice, `50\%\_off`, createdAfter, 21},
+		},
+		{
+			name:         "include archived",
+			filter:       ProductFilter{IncludeArchived: true},
+			expectedSQL:  "SELECT id, name, price, image, description, attributes, average_rating, review_count, external_id, archived_at, created_at FROM products ORDER BY id LIMIT $1",
+			expectedArgs: []any{21},
+		},
+		{
+			name:         "attributes",
+			filter:       ProductFilter{Attributes: map[string][]string{"material": {"cotton"}, "brand": {"Acme", "Globex"}}},
+			expectedSQL:  "SELECT id, name, price, image, description, attributes, average_rating, review_count, external_id, archived_at, created_at FROM products WHERE archived_at IS NULL AND (attributes @> $1 OR attributes @> $2) AND attributes @> $3 ORDER BY id LIMIT $4",
+			expectedArgs: []any{map[string]string{"brand": "Acme"}, map[string]string{"brand": "Globex"}, map[string]string{"material": "cotton"}, 21},
+		},
+		{
+			name:         "sort",
+			filter:       ProductFilter{Sort: []SortField{{Field: "price"}, {Field: "created_at", Desc: true}}},
+			expectedSQL:  "SELECT id, name, price, image, description, attributes, average_rating, review_count, external_id, archived_at, created_at FROM products WHERE archived_at IS NULL ORDER BY price, created_at DESC, id LIMIT $1",
+			expectedArgs: []any{21},
+		},
+		{
+			name:         "sort after cursor",
+			filter:       ProductFilter{Sort: []SortField{{Field: "price", Desc: true}}},
+			cursor:       cursor{ID: 7, Sort: "-price,id", Values: []string{"19.99", "7"}},
+			expectedSQL:  "SELECT id, name, price, image, description, attributes, average_rating, review_count, external_id, archived_at, created_at FROM products WHERE archived_at IS NULL AND ((price < $1) OR (price = $1 AND id > $2)) ORDER BY price DESC, id LIMIT $3",
+			expectedArgs: []any{"19.99", "7", 21},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := buildProductsQuery(tt.filter, tt.cursor, 21)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSQL, sql)
+			assert.Equal(t, tt.expectedArgs, args)
+		})
+	}
+}
+
+func TestBuildProductsQueryInvalid(t *testing.T) {
+	_, _, err := buildProductsQuery(ProductFilter{Sort: []SortField{{Field: "password"}}}, cursor{}, 21)
+	require.ErrorIs(t, err, ErrInvalidFilter)
+
+	_, _, err = buildProductsQuery(ProductFilter{Sort: []SortField{{Field: "price"}, {Field: "price"}}}, cursor{}, 21)
+	require.ErrorIs(t, err, ErrInvalidFilter)
+
+	// A cursor issued for one ordering cannot be used with another
+	_, _, err = buildProductsQuery(ProductFilter{Sort: []SortField{{Field: "name"}}}, cursor{ID: 7, Sort: "-price,id", Values: []string{"19.99", "7"}}, 21)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestBuildFacetsQuery(t *testing.T) {
+	sql, args := buildFacetsQuery(ProductFilter{Attributes: map[string][]string{"brand": {"Acme"}}}, 50)
+	assert.Contains(t, sql, "WHERE archived_at IS NULL AND (a.key = $1 OR attributes @> $2)")
+	assert.Contains(t, sql, "WHERE rank <= $3")
+	assert.Equal(t, []any{"brand", map[string]string{"brand": "Acme"}, 50}, args)
+}
+
+func TestProductCursor(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	p := Product{ID: 3, Name: "Lamp", Price: money.MustParse("19.5", "USD"), CreatedAt: createdAt}
+	keys := []SortField{{Field: "price"}, {Field: "created_at", Desc: true}, {Field: "id"}}
+
+	c := productCursor(p, keys)
+	assert.Equal(t, int64(3), c.ID)
+	assert.Equal(t, "price,-created_at,id", c.Sort)
+	assert.Equal(t, []string{"19.50", "2024-05-01T12:30:00Z", "3"}, c.Values)
+
+	p.AverageRating = 4.5
+	c = productCursor(p, []SortField{{Field: "rating", Desc: true}, {Field: "id"}})
+	assert.Equal(t, "-rating,id", c.Sort)
+	assert.Equal(t, []string{"4.50", "3"}, c.Values)
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RatingSummary describes how a product's reviews are spread over the star levels
+type RatingSummary struct {
+	ProductID int64 `json:"product_id"`
+	Count     int64 `json:"count"`
+	// Histogram counts the reviews of each star level, from 1 to 5
+	Histogram map[int]int64 `json:"histogram"`
+	// Mean and Median are nil while the product has no reviews
+	Mean   *float64 `json:"mean"`
+	Median *float64 `json:"median"`
+	// BayesianScore is the mean pulled towards the prior mean, the fewer the
+	// reviews the stronger the pull, so that a single five star review does
+	// not outrank many good ones
+	BayesianScore float64 `json:"bayesian_score"`
+}
+
+// RatingPrior is the rating assumed for a product before its reviews are
+// taken into account, weighted as if it came from Weight reviews
+type RatingPrior struct {
+	Mean   float64
+	Weight float64
+}
+
+// GetRatingSummary summarizes the approved reviews of a product. Summaries are cached
+// until the next review of the product is written through this DB.
+func (db *DB) GetRatingSummary(ctx context.Context, productId int64, prior RatingPrior) (RatingSummary, error) {
+	summary, ok, version := db.ratingSummaries.get(productId)
+	if !ok {
+		var err error
+		summary, err = db.queryRatingSummary(ctx, productId)
+		if err != nil {
+			return RatingSummary{}, err
+		}
+		db.ratingSummaries.put(productId, summary, version)
+	}
+
+	// The score depends on the prior, so it is left out of the cache
+	summary.Histogram = maps.Clone(summary.Histogram)
+	summary.BayesianScore = prior.Mean
+	if prior.Weight+float64(summary.Count) > 0 {
+		total := 0.0
+		for stars, count := range summary.Histogram {
+			total += float64(stars * int(count))
+		}
+		summary.BayesianScore = (prior.Weight*prior.Mean + total) / (prior.Weight + float64(summary.Count))
+	}
+	return summary, nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func (db *DB) queryRatingSummary(ctx context.Context, productId int64) (RatingSummary, error) {
+	summary := RatingSummary{ProductID: productId}
+	var histogram [5]int64
+	err := db.pool.QueryRow(ctx, `
+	SELECT
+		count(r.id),
+		count(r.id) FILTER (WHERE r.stars = 1),
+		count(r.id) FILTER (WHERE r.stars = 2),
+		count(r.id) FILTER (WHERE r.stars = 3),
+		count(r.id) FILTER (WHERE r.stars = 4),
+		count(r.id) FILTER (WHERE r.stars = 5),
+		avg(r.stars)::float8,
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY r.stars)
+	FROM products p
+	LEFT JOIN reviews r ON r.product_id = p.id AND r.status = 'approved'
+	WHERE p.id = $1
+	GROUP BY p.id
+	`, productId).Scan(&summary.Count, &histogram[0], &histogram[1], &histogram[2], &histogram[3], &histogram[4],
+		&summary.Mean, &summary.Median)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RatingSummary{}, fmt.Errorf("product with id %d %w", productId, ErrNotFound)
+	}
+	if err != nil {
+		return RatingSummary{}, fmt.Errorf("failed to query rating summary: %w", err)
+	}
+
+	summary.Histogram = make(map[int]int64, len(histogram))
+	for i, count := range histogram {
+		summary.Histogram[i+1] = count
+	}
+	return summary, nil
+}
+
+// ratingSummaryCache holds rating summaries until a review of their product
+// is written. Each product has a version bumped on every write, a summary
+// queried before a write is not stored after it. The cache is per process,
+// review writes made by other processes are not seen.
+type ratingSummaryCache struct {
+	mu        sync.Mutex
+	summaries map[int64]RatingSummary
+	versions  map[int64]uint64
+}
+
+// get returns the cached summary of a product if there is one, along with
+// the version to put a freshly queried summary at
+func (c *ratingSummaryCache) get(productId int64) (RatingSummary, bool, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary, ok := c.summaries[productId]
+	return summary, ok, c.versions[productId]
+}
+
+func (c *ratingSummaryCache) put(productId int64, summary RatingSummary, version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.versions[productId] != version {
+		return
+	}
+	if c.summaries == nil {
+		c.summaries = make(map[int64]RatingSummary)
+	}
+	c.summaries[productId] = summary
+}
+
+func (c *ratingSummaryCache) invalidate(productIds ...int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.versions == nil {
+		c.versions = make(map[int64]uint64)
+	}
+	for _, id := range productIds {
+		delete(c.summaries, id)
+		c.versions[id]++
+	}
+}
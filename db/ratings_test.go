@@ -0,0 +1,135 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductRatings(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150"},
+		{ID: 2, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150"},
+		{ID: 3, Name: "Office Chair", Price: money.MustParse("149.99", "USD"), Image: "https://via.placeholder.com/150"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []Review{
+		{ID: 1, UserId: "u1", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 5},
+		{ID: 2, UserId: "u2", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 4},
+		{ID: 3, UserId: "u3", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 4},
+		{ID: 4, UserId: "u4", ProductID: 2, ReviewTitle: "Title", ReviewContent: "Content", Stars: 5},
+	}
+	err = PopulateTestData(ctx, db, "reviews", testReviews)
+	require.NoError(t, err)
+
+	product, err := db.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), product.ReviewCount)
+	assert.Equal(t, 4.33, product.AverageRating)
+
+	_, err = db.PostReview(ctx, ClientReview{ProductID: 3, ReviewTitle: "Title", ReviewContent: "Content", Stars: 2}, "u1", ReviewApproved)
+	require.NoError(t, err)
+
+	// Moving a review updates both products, deleting one updates its product
+	_, err = db.pool.Exec(ctx, "UPDATE reviews SET product_id = 2, stars = 3 WHERE id = 1")
+	require.NoError(t, err)
+	_, err = db.pool.Exec(ctx, "DELETE FROM reviews WHERE id = 2")
+	require.NoError(t, err)
+
+	products, _, err := db.GetProducts(ctx, ProductFilter{Sort: []SortField{{Field: "rating", Desc: true}}}, Page{})
+	require.NoError(t, err)
+	require.Len(t, products, 3)
+	assert.Equal(t, int64(1), products[0].ID)
+	assert.Equal(t, 4.0, products[0].AverageRating)
+	assert.Equal(t, int64(1), products[0].ReviewCount)
+	assert.Equal(t, int64(2), products[1].ID)
+	assert.Equal(t, 4.0, products[1].AverageRating)
+	assert.Equal(t, int64(2), products[1].ReviewCount)
+	assert.Equal(t, int64(3), products[2].ID)
+	assert.Equal(t, 2.0, products[2].AverageRating)
+
+	// The last review gone, the average goes back to 0
+	_, err = db.pool.Exec(ctx, "DELETE FROM reviews WHERE product_id = 3")
+	require.NoError(t, err)
+	product, err = db.GetProduct(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), product.ReviewCount)
+	assert.Equal(t, 0.0, product.AverageRating)
+}
+
+func TestGetRatingSummary(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150"},
+		{ID: 2, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []Review{
+		{ID: 1, UserId: "u1", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 5},
+		{ID: 2, UserId: "u2", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 4},
+		{ID: 3, UserId: "u3", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 1},
+	}
+	err = PopulateTestData(ctx, db, "reviews", testReviews)
+	require.NoError(t, err)
+
+	prior := RatingPrior{Mean: 3, Weight: 2}
+	summary, err := db.GetRatingSummary(ctx, 1, prior)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), summary.Count)
+	assert.Equal(t, map[int]int64{1: 1, 2: 0, 3: 0, 4: 1, 5: 1}, summary.Histogram)
+	require.NotNil(t, summary.Mean)
+	assert.InDelta(t, 3.33, *summary.Mean, 0.01)
+	require.NotNil(t, summary.Median)
+	assert.Equal(t, 4.0, *summary.Median)
+	assert.InDelta(t, 3.2, summary.BayesianScore, 0.001)
+
+	// Posting a review drops the cached summary
+	_, err = db.PostReview(ctx, ClientReview{ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 2}, "u4", ReviewApproved)
+	require.NoError(t, err)
+	summary, err = db.GetRatingSummary(ctx, 1, prior)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), summary.Count)
+	assert.Equal(t, 3.0, *summary.Median)
+
+	// Without reviews the score is the prior mean
+	summary, err = db.GetRatingSummary(ctx, 2, prior)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), summary.Count)
+	assert.Nil(t, summary.Mean)
+	assert.Nil(t, summary.Median)
+	assert.Equal(t, 3.0, summary.BayesianScore)
+
+	_, err = db.GetRatingSummary(ctx, 42, prior)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRatingSummaryCache(t *testing.T) {
+	var cache ratingSummaryCache
+
+	_, ok, version := cache.get(1)
+	require.False(t, ok)
+	cache.put(1, RatingSummary{ProductID: 1, Count: 1}, version)
+	summary, ok, _ := cache.get(1)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), summary.Count)
+
+	// A summary queried before a write is not stored after it
+	cache.invalidate(1)
+	_, ok, version = cache.get(1)
+	require.False(t, ok)
+	cache.invalidate(1)
+	cache.put(1, RatingSummary{ProductID: 1, Count: 1}, version)
+	_, ok, _ = cache.get(1)
+	assert.False(t, ok)
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultRelatedLimit is how many related products are returned when no limit is given
+	DefaultRelatedLimit = 10
+	// maxStoredRelations caps the relations kept per product by a refresh
+	maxStoredRelations = 50
+)
+
+// Sources of a related product
+const (
+	// RelatedByReviews marks products reviewed by the same users
+	RelatedByReviews = "reviews"
+	// RelatedByCategory marks products sharing a category, used to fill in
+	// when there are not enough co-reviews
+	RelatedByCategory = "category"
+)
+
+// RelatedProduct is a product recommended alongside another one
+type RelatedProduct struct {
+	Product
+	// Score is the cosine similarity of the two products' reviewers, from
+	// 0 to 1. Products related by category score 0.
+	Score  float64 `db:"score" json:"score"`
+	Source string  `db:"source" json:"source"`
+}
+
+// RefreshProductRelations recomputes the related products of every product
+// from approved reviews, two products being related when the same users
+// reviewed both. It keeps the best scored relations of each product and returns how
+// many were stored. Readers keep seeing the previous relations until the
+// refresh commits.
+func (db *DB) RefreshProductRelations(ctx context.Context) (int64, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM product_relations"); err != nil {
+		return 0, fmt.Errorf("failed to clear product relations: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+	WITH reviewers AS (
+		SELECT DISTINCT user_id, product_id FROM reviews WHERE status = 'approved'
+	), counts AS (
+		SELECT product_id, count(*) AS reviewers FROM reviewers GROUP BY product_id
+	), pairs AS (
+		SELECT a.product_id, b.product_id AS related_id, count(*) AS shared
+		FROM reviewers a
+		JOIN reviewers b ON b.user_id = a.user_id AND b.product_id <> a.product_id
+		GROUP BY a.product_id, b.product_id
+	), scored AS (
+		SELECT p.product_id, p.related_id, p.shared / sqrt((ca.reviewers * cb.reviewers)::float8) AS score
+		FROM pairs p
+		JOIN counts ca ON ca.product_id = p.product_id
+		JOIN counts cb ON cb.product_id = p.related_id
+	), ranked AS (
+		SELECT *, row_number() OVER (PARTITION BY product_id ORDER BY score DESC, related_id) AS rank
+		FROM scored
+	)
+	INSERT INTO product_relations (product_id, related_id, score)
+	SELECT product_id, related_id, score FROM ranked WHERE rank <= $1
+	`, maxStoredRelations)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute product relations: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit product relations: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetRelatedProducts returns up to limit products related to a product, best
+// scored first. When co-reviews do not yield enough of them, the rest is
+// filled with products sharing a category. Archived products are left out.
+func (db *DB) GetRelatedProducts(ctx context.Context, productID int64, limit int) ([]RelatedProduct, error) {
+	if limit <= 0 {
+		limit = DefaultRelatedLimit
+	}
+
+	var exists bool
+	err := db.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+
+	rows, err := db.pool.Query(ctx, `
+	SELECT `+productColumns+`, r.score, $3::text AS source
+	FROM product_relations r
+	JOIN products ON products.id = r.related_id
+	WHERE r.product_id = $1 AND archived_at IS NULL
+	ORDER BY r.score DESC, id
+	LIMIT $2
+	`, productID, limit, RelatedByReviews)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related products: %w", err)
+	}
+	related, err := pgx.CollectRows(rows, pgx.RowToStructByName[RelatedProduct])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related products: %w", err)
+	}
+
+	if len(related) < limit {
+		exclude := []int64{productID}
+		for _, p := range related {
+			exclude = append(exclude, p.ID)
+		}
+		rows, err := db.pool.Query(ctx, `
+		SELECT `+productColumns+`, 0::float8 AS score, $4::text AS source
+		FROM products
+		WHERE id IN (
+			SELECT b.product_id FROM product_categories a
+			JOIN product_categories b ON b.category_id = a.category_id
+			WHERE a.product_id = $1
+		) AND id <> ALL($2) AND archived_at IS NULL
+		ORDER BY id
+		LIMIT $3
+		`, productID, exclude, limit-len(related), RelatedByCategory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query related products: %w", err)
+		}
+		byCategory, err := pgx.CollectRows(rows, pgx.RowToStructByName[RelatedProduct])
+		if err != nil {
+			return nil, fmt.Errorf("failed to query related products: %w", err)
+		}
+		related = append(related, byCategory...)
+	}
+
+	refs := make([]*Product, len(related))
+	for i := range related {
+		refs[i] = &related[i].Product
+	}
+	if err := db.attachAvailability(ctx, refs); err != nil {
+		return nil, err
+	}
+	return related, nil
+}
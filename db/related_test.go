@@ -0,0 +1,77 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelatedProducts(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150"},
+		{ID: 2, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150"},
+		{ID: 3, Name: "Office Chair", Price: money.MustParse("149.99", "USD"), Image: "https://via.placeholder.com/150"},
+		{ID: 4, Name: "Pine Desk", Price: money.MustParse("99.99", "USD"), Image: "https://via.placeholder.com/150"},
+		{ID: 5, Name: "Glass Desk", Price: money.MustParse("249.99", "USD"), Image: "https://via.placeholder.com/150"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testCategories := []Category{
+		{ID: 1, Name: "Desks", Slug: "desks"},
+	}
+	err = PopulateTestData(ctx, db, "categories", testCategories)
+	require.NoError(t, err)
+	for _, id := range []int64{1, 4, 5} {
+		err = db.SetProductCategories(ctx, id, []int64{1})
+		require.NoError(t, err)
+	}
+
+	testReviews := []Review{
+		{ID: 1, UserId: "u1", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 5},
+		{ID: 2, UserId: "u1", ProductID: 2, ReviewTitle: "Title", ReviewContent: "Content", Stars: 4},
+		{ID: 3, UserId: "u2", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 4},
+		{ID: 4, UserId: "u2", ProductID: 2, ReviewTitle: "Title", ReviewContent: "Content", Stars: 3},
+		{ID: 5, UserId: "u2", ProductID: 3, ReviewTitle: "Title", ReviewContent: "Content", Stars: 5},
+		{ID: 6, UserId: "u3", ProductID: 3, ReviewTitle: "Title", ReviewContent: "Content", Stars: 2},
+	}
+	err = PopulateTestData(ctx, db, "reviews", testReviews)
+	require.NoError(t, err)
+
+	// Before the first refresh only the category fallback is available
+	related, err := db.GetRelatedProducts(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, related, 2)
+	assert.Equal(t, RelatedByCategory, related[0].Source)
+
+	stored, err := db.RefreshProductRelations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), stored)
+
+	_, err = db.ArchiveProduct(ctx, 5)
+	require.NoError(t, err)
+
+	related, err = db.GetRelatedProducts(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, related, 3)
+	assert.Equal(t, int64(2), related[0].ID)
+	assert.InDelta(t, 1.0, related[0].Score, 1e-9)
+	assert.Equal(t, RelatedByReviews, related[0].Source)
+	assert.Equal(t, int64(3), related[1].ID)
+	assert.InDelta(t, 0.5, related[1].Score, 1e-9)
+	assert.Equal(t, int64(4), related[2].ID)
+	assert.Equal(t, RelatedByCategory, related[2].Source)
+
+	related, err = db.GetRelatedProducts(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, related, 1)
+	assert.Equal(t, int64(2), related[0].ID)
+
+	_, err = db.GetRelatedProducts(ctx, 42, 10)
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SearchResult is a product matched by a full-text search
+type SearchResult struct {
+	Product
+	Rank float64 `db:"rank" json:"rank"`
+	// NameHighlight is the HTML escaped name with the matching words marked
+	NameHighlight string `db:"name_highlight" json:"name_highlight"`
+	// Snippet is an HTML escaped excerpt of the description with the
+	// matching words marked
+	Snippet string `db:"snippet" json:"snippet"`
+}
+
+// Suggestion is a product name offered while the user is typing
+type Suggestion struct {
+	ID    int64   `db:"id" json:"id"`
+	Name  string  `db:"name" json:"name"`
+	Score float64 `db:"score" json:"score"`
+}
+
+// DefaultSuggestLimit is how many suggestions are returned when no limit is given
+const DefaultSuggestLimit = 10
+
+// headlineOptions configures the ts_headline snippets returned with search
+// results, nameHighlightOptions the highlighted names
+const (
+	headlineOptions      = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+	nameHighlightOptions = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+)
+
+// SearchProducts runs a full-text search over product names and descriptions
+// and returns the best matches first. The query uses web search syntax, so
+// quoted phrases, "or" and "-" exclusions are supported. Names and snippets
+// are escaped before the matches are marked, so they are safe to render as
+// HTML.
+func (db *DB) SearchProducts(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	limit = Page{Limit: limit}.limit()
+
+	rows, err := db.pool.Query(ctx, `
+	SELECT `+productColumns+`,
+		ts_rank(search_vector, query) AS rank,
+		ts_headline('english', html_escape(name), query, $3) AS name_highlight,
+		ts_headline('english', html_escape(description), query, $2) AS snippet
+	FROM products, websearch_to_tsquery('english', $1) AS query
+	WHERE search_vector @@ query AND archived_at IS NULL
+	ORDER BY rank DESC, id
+	LIMIT $4
+	`, query, headlineOptions, nameHighlightOptions, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		result, err := pgx.RowToStructByName[SearchResult](rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	refs := make([]*Product, len(results))
+	for i := range results {
+		refs[i] = &results[i].Product
+	}
+	if err := db.attachAvailability(ctx, refs); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SuggestProducts returns product names for typeahead. Names starting with
+// prefix come first, followed by names containing a word whose trigram
+// similarity to prefix reaches minSimilarity, so typos still match.
+func (db *DB) SuggestProducts(ctx context.Context, prefix string, minSimilarity float64, limit int) ([]Suggestion, error) {
+	if limit <= 0 {
+		limit = DefaultSuggestLimit
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The <% operator reads its threshold from this setting, scoping it to
+	// the transaction keeps the trigram index usable for the lookup
+	_, err = tx.Exec(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)",
+		strconv.FormatFloat(minSimilarity, 'f', -1, 64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set similarity threshold: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+	SELECT id, name, word_similarity($1, name) AS score
+	FROM products
+	WHERE (name ILIKE $2 || '%' OR $1 <% name) AND archived_at IS NULL
+	ORDER BY name ILIKE $2 || '%' DESC, score DESC, name
+	LIMIT $3
+	`, prefix, escapeLike(prefix), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	var suggestions []Suggestion
+	for rows.Next() {
+		suggestion, err := pgx.RowToStructByName[Suggestion](rows)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query suggestions: %w", err)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchProducts(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150", Description: "A sturdy desk made from solid oak"},
+		{ID: 2, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Warm light for late nights"},
+		{ID: 3, Name: "Office Chair", Price: money.MustParse("149.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Pairs well with any desk"},
+		{ID: 4, Name: "Bookshelf", Price: money.MustParse("89.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Five shelves of pine"},
+		{ID: 5, Name: "Pine <b>Stool</b>", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "<script>alert(1)</script> A stool & a footrest"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	results, err := db.SearchProducts(ctx, "desks", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// Name matches are weighted above description matches
+	assert.Contains(t, []int64{1, 2}, results[0].ID)
+	assert.Equal(t, int64(3), results[2].ID)
+	for i, r := range results {
+		assert.Positive(t, r.Rank)
+		if i > 0 {
+			assert.LessOrEqual(t, r.Rank, results[i-1].Rank)
+		}
+	}
+	assert.Contains(t, results[2].Snippet, "<mark>desk</mark>")
+	assert.Equal(t, "Office Chair", results[2].NameHighlight)
+	for _, r := range results[:2] {
+		assert.Contains(t, r.NameHighlight, "<mark>Desk</mark>")
+	}
+
+	// Markup in names and descriptions is escaped, only the marks are HTML
+	results, err = db.SearchProducts(ctx, "stool", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Pine &lt;b&gt;<mark>Stool</mark>&lt;/b&gt;", results[0].NameHighlight)
+	assert.Contains(t, results[0].Snippet, "&lt;script&gt;")
+	assert.Contains(t, results[0].Snippet, "<mark>stool</mark> &amp; a footrest")
+	assert.NotContains(t, results[0].Snippet, "<script>")
+
+	results, err = db.SearchProducts(ctx, "desk -lamp", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	results, err = db.SearchProducts(ctx, "sofa", 10)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestSuggestProducts(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Floor Lamp", Price: money.MustParse("59.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Desktop Stand", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 4, Name: "Bookshelf", Price: money.MustParse("89.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 4"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	suggestions, err := db.SuggestProducts(ctx, "desk", 0.3, 10)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(suggestions), 2)
+	// Prefix matches come first
+	assert.ElementsMatch(t, []int64{1, 3}, []int64{suggestions[0].ID, suggestions[1].ID})
+
+	// A typo still finds the lamps
+	suggestions, err = db.SuggestProducts(ctx, "lmap", 0.1, 10)
+	require.NoError(t, err)
+	var ids []int64
+	for _, s := range suggestions {
+		ids = append(ids, s.ID)
+	}
+	assert.Contains(t, ids, int64(1))
+	assert.Contains(t, ids, int64(2))
+
+	// A strict threshold drops the fuzzy matches
+	suggestions, err = db.SuggestProducts(ctx, "lmap", 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+
+	suggestions, err = db.SuggestProducts(ctx, "desk", 0.3, 1)
+	require.NoError(t, err)
+	assert.Len(t, suggestions, 1)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -24,7 +25,7 @@ func InitializeTestDB(t *testing.T, db *DB) {
 		CREATE TABLE IF NOT EXISTS products (
 			id SERIAL PRIMARY KEY,
 			name TEXT NOT NULL,
-			price FLOAT NOT NULL,
+			price DECIMAL(10, 2) NOT NULL,
 			image TEXT NOT NULL,
 			description TEXT NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -178,13 +179,18 @@ func PopulateTestData(ctx context.Context, db *DB, tableName string, data any) e
 	}
 
 	var fields []string
+	var fieldIndexes []int
 	for i := range elemType.NumField() {
 		field := elemType.Field(i)
 		dbTag := field.Tag.Get("db")
-		if dbTag == "" || dbTag == "-" {
+		if dbTag == "-" {
+			continue
+		}
+		if dbTag == "" {
 			dbTag = strings.ToLower(field.Name)
 		}
 		fields = append(fields, dbTag)
+		fieldIndexes = append(fieldIndexes, i)
 	}
 
 	var sb strings.Builder
@@ -203,11 +209,11 @@ func PopulateTestData(ctx context.Context, db *DB, tableName string, data any) e
 	for i := range val.Len() {
 		paramOffset := i * len(fields)
 		placeholders := make([]string, len(fields))
-		for j := range fields {
+		for j, fieldIndex := range fieldIndexes {
 			placeholders[j] = fmt.Sprintf("$%d", paramOffset+j+1)
 
 			// Extract field value
-			fieldValue := val.Index(i).Field(j).Interface()
+			fieldValue := val.Index(i).Field(fieldIndex).Interface()
 			args = append(args, fieldValue)
 		}
 		placeholderGroups[i] = fmt.Sprintf("(%s)", strings.Join(placeholders, ", "))
@@ -220,6 +226,17 @@ func PopulateTestData(ctx context.Context, db *DB, tableName string, data any) e
 		return fmt.Errorf("failed to insert data: %w", err)
 	}
 
+	// Rows were inserted with explicit ids, move the serial sequence past
+	// them so rows created afterwards by the code under test do not collide
+	if slices.Contains(fields, "id") {
+		_, err = tx.Exec(ctx, fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%s', 'id'), (SELECT MAX(id) FROM %s))",
+			tableName, tableName))
+		if err != nil {
+			return fmt.Errorf("failed to advance id sequence: %w", err)
+		}
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
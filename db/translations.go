@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ProductTranslation holds the name and description of a product in a
+// locale other than the default one
+type ProductTranslation struct {
+	ProductID   int64     `db:"product_id" json:"product_id"`
+	Locale      string    `db:"locale" json:"locale"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ClientTranslation is the payload accepted when setting a translation
+type ClientTranslation struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+const translationColumns = "product_id, locale, name, description, updated_at"
+
+// GetProductTranslations returns the translations of a product ordered by locale
+func (db *DB) GetProductTranslations(ctx context.Context, productID int64) ([]ProductTranslation, error) {
+	var exists bool
+	err := db.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+
+	rows, err := db.pool.Query(ctx,
+		"SELECT "+translationColumns+" FROM product_translations WHERE product_id = $1 ORDER BY locale",
+		productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query translations: %w", err)
+	}
+	translations, err := pgx.CollectRows(rows, pgx.RowToStructByName[ProductTranslation])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query translations: %w", err)
+	}
+	return translations, nil
+}
+
+// SetProductTranslation creates or replaces the translation of a product in a locale
+func (db *DB) SetProductTranslation(ctx context.Context, productID int64, locale string, t ClientTranslation) (ProductTranslation, error) {
+	rows, err := db.pool.Query(ctx,
+		`INSERT INTO product_translations (product_id, locale, name, description)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (product_id, locale) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, updated_at = now()
+		RETURNING `+translationColumns,
+		productID, locale, t.Name, t.Description)
+	if err != nil {
+		return ProductTranslation{}, fmt.Errorf("failed to set translation: %w", err)
+	}
+	translation, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ProductTranslation])
+	if isForeignKeyViolation(err) {
+		return ProductTranslation{}, fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+	if err != nil {
+		return ProductTranslation{}, fmt.Errorf("failed to set translation: %w", translateError(err))
+	}
+	return translation, nil
+}
+
+// DeleteProductTranslation removes the translation of a product in a locale
+func (db *DB) DeleteProductTranslation(ctx context.Context, productID int64, locale string) error {
+	tag, err := db.pool.Exec(ctx,
+		"DELETE FROM product_translations WHERE product_id = $1 AND locale = $2", productID, locale)
+	if err != nil {
+		return fmt.Errorf("failed to delete translation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("translation %s of product %d %w", locale, productID, ErrNotFound)
+	}
+	return nil
+}
+
+// Localize replaces the names and descriptions of products with their
+// translation in locale. Products without one keep their default locale
+// text. The locale each product ends up in is recorded on it.
+func (db *DB) Localize(ctx context.Context, locale, defaultLocale string, products []*Product) error {
+	for _, p := range products {
+		p.Locale = defaultLocale
+	}
+	if locale == defaultLocale || len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	rows, err := db.pool.Query(ctx,
+		"SELECT "+translationColumns+" FROM product_translations WHERE locale = $1 AND product_id = ANY($2)",
+		locale, ids)
+	if err != nil {
+		return fmt.Errorf("failed to query translations: %w", err)
+	}
+	translations, err := pgx.CollectRows(rows, pgx.RowToStructByName[ProductTranslation])
+	if err != nil {
+		return fmt.Errorf("failed to query translations: %w", err)
+	}
+
+	byProduct := make(map[int64]ProductTranslation, len(translations))
+	for _, t := range translations {
+		byProduct[t.ProductID] = t
+	}
+	for _, p := range products {
+		if t, ok := byProduct[p.ID]; ok {
+			p.Name = t.Name
+			p.Description = t.Description
+			p.Locale = locale
+		}
+	}
+	return nil
+}
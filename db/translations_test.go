@@ -0,0 +1,56 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductTranslations(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150", Description: "A sturdy desk"},
+		{ID: 2, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "A bright lamp"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	_, err = db.SetProductTranslation(ctx, 1, "fr", ClientTranslation{Name: "Bureau", Description: "Un bureau"})
+	require.NoError(t, err)
+	// Setting it again replaces it
+	saved, err := db.SetProductTranslation(ctx, 1, "fr", ClientTranslation{Name: "Bureau en chêne", Description: "Un bureau solide"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bureau en chêne", saved.Name)
+	_, err = db.SetProductTranslation(ctx, 1, "de", ClientTranslation{Name: "Eichenschreibtisch"})
+	require.NoError(t, err)
+
+	_, err = db.SetProductTranslation(ctx, 42, "fr", ClientTranslation{Name: "Lampe"})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	translations, err := db.GetProductTranslations(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, translations, 2)
+	assert.Equal(t, "de", translations[0].Locale)
+	assert.Equal(t, "fr", translations[1].Locale)
+
+	desk, lamp := testProducts[0], testProducts[1]
+	err = db.Localize(ctx, "fr", "en", []*Product{&desk, &lamp})
+	require.NoError(t, err)
+	assert.Equal(t, "Bureau en chêne", desk.Name)
+	assert.Equal(t, "Un bureau solide", desk.Description)
+	assert.Equal(t, "fr", desk.Locale)
+	assert.Equal(t, "Desk Lamp", lamp.Name)
+	assert.Equal(t, "en", lamp.Locale)
+
+	err = db.DeleteProductTranslation(ctx, 1, "fr")
+	require.NoError(t, err)
+	err = db.DeleteProductTranslation(ctx, 1, "fr")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = db.GetProductTranslations(ctx, 42)
+	require.ErrorIs(t, err, ErrNotFound)
+}
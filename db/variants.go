@@ -0,0 +1,117 @@
+package db
+
+import (
+	"catalogapi/money"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Variant is a purchasable version of a product, such as a size and color
+// combination. A nil Price or Image means the product's own value applies.
+type Variant struct {
+	ID        int64             `db:"id" json:"id"`
+	ProductID int64             `db:"product_id" json:"product_id"`
+	SKU       string            `db:"sku" json:"sku"`
+	Options   map[string]string `db:"options" json:"options"`
+	Price     *money.Money      `db:"price" json:"price"`
+	Image     *string           `db:"image" json:"image"`
+	CreatedAt time.Time         `db:"created_at" json:"created_at"`
+	// QuantityAvailable is the variant's unreserved stock
+	QuantityAvailable int64 `db:"-" json:"quantity_available"`
+	InStock           bool  `db:"-" json:"in_stock"`
+}
+
+// ClientVariant is the payload accepted when creating or replacing a variant
+type ClientVariant struct {
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+	Price   *money.Money      `json:"price"`
+	Image   *string           `json:"image"`
+}
+
+const variantColumns = "id, product_id, sku, options, price, image, created_at"
+
+// GetProductVariants returns all variants of a product ordered by id
+func (db *DB) GetProductVariants(ctx context.Context, productID int64) ([]Variant, error) {
+	rows, err := db.pool.Query(ctx,
+		"SELECT "+variantColumns+" FROM product_variants WHERE product_id = $1 ORDER BY id",
+		productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variants: %w", err)
+	}
+	variants, err := pgx.CollectRows(rows, pgx.RowToStructByName[Variant])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variants: %w", err)
+	}
+
+	available, err := db.variantAvailability(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range variants {
+		variants[i].QuantityAvailable = available[variants[i].ID]
+		variants[i].InStock = variants[i].QuantityAvailable > 0
+	}
+	return variants, nil
+}
+
+func (db *DB) CreateVariant(ctx context.Context, productID int64, v ClientVariant) (Variant, error) {
+	rows, err := db.pool.Query(ctx,
+		`INSERT INTO product_variants (product_id, sku, options, price, image)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+variantColumns,
+		productID, v.SKU, variantOptions(v.Options), v.Price, v.Image)
+	if err != nil {
+		return Variant{}, fmt.Errorf("failed to insert variant: %w", err)
+	}
+	variant, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Variant])
+	if isForeignKeyViolation(err) {
+		return Variant{}, fmt.Errorf("product with id %d %w", productID, ErrNotFound)
+	}
+	if err != nil {
+		return Variant{}, fmt.Errorf("failed to insert variant: %w", translateError(err))
+	}
+	return variant, nil
+}
+
+func (db *DB) UpdateVariant(ctx context.Context, productID, id int64, v ClientVariant) (Variant, error) {
+	rows, err := db.pool.Query(ctx,
+		`UPDATE product_variants SET sku = $3, options = $4, price = $5, image = $6
+		WHERE product_id = $1 AND id = $2
+		RETURNING `+variantColumns,
+		productID, id, v.SKU, variantOptions(v.Options), v.Price, v.Image)
+	if err != nil {
+		return Variant{}, fmt.Errorf("failed to update variant: %w", err)
+	}
+	variant, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Variant])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Variant{}, fmt.Errorf("variant with id %d of product %d %w", id, productID, ErrNotFound)
+	}
+	if err != nil {
+		return Variant{}, fmt.Errorf("failed to update variant: %w", translateError(err))
+	}
+	return variant, nil
+}
+
+func (db *DB) DeleteVariant(ctx context.Context, productID, id int64) error {
+	tag, err := db.pool.Exec(ctx, "DELETE FROM product_variants WHERE product_id = $1 AND id = $2", productID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete variant: %w", translateError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("variant with id %d of product %d %w", id, productID, ErrNotFound)
+	}
+	return nil
+}
+
+// variantOptions keeps a missing options object from being stored as JSON null
+func variantOptions(options map[string]string) map[string]string {
+	if options == nil {
+		return map[string]string{}
+	}
+	return options
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"catalogapi/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateVariant(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	variant, err := db.CreateVariant(ctx, 1, ClientVariant{
+		SKU:     "TS-RED-M",
+		Options: map[string]string{"color": "red", "size": "M"},
+		Price:   ptr(money.MustParse("21.99", "USD")),
+		Image:   ptr("https://via.placeholder.com/300"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), variant.ProductID)
+	assert.Equal(t, "TS-RED-M", variant.SKU)
+	assert.Equal(t, map[string]string{"color": "red", "size": "M"}, variant.Options)
+	require.NotNil(t, variant.Price)
+	assert.Equal(t, money.MustParse("21.99", "USD"), *variant.Price)
+
+	variant, err = db.CreateVariant(ctx, 1, ClientVariant{SKU: "TS-BLUE-M"})
+	require.NoError(t, err)
+	assert.Nil(t, variant.Price)
+	assert.Nil(t, variant.Image)
+	assert.Empty(t, variant.Options)
+
+	_, err = db.CreateVariant(ctx, 1, ClientVariant{SKU: "TS-RED-M"})
+	require.ErrorIs(t, err, ErrConflict)
+
+	_, err = db.CreateVariant(ctx, 42, ClientVariant{SKU: "MISSING"})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGetProductVariants(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testVariants := []Variant{
+		{ID: 1, ProductID: 1, SKU: "TS-S", Options: map[string]string{"size": "S"}},
+		{ID: 2, ProductID: 1, SKU: "TS-M", Options: map[string]string{"size": "M"}},
+		{ID: 3, ProductID: 2, SKU: "HD-M", Options: map[string]string{"size": "M"}},
+	}
+	err = PopulateTestData(ctx, db, "product_variants", testVariants)
+	require.NoError(t, err)
+
+	variants, err := db.GetProductVariants(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+	assert.Equal(t, "TS-S", variants[0].SKU)
+	assert.Equal(t, "TS-M", variants[1].SKU)
+
+	variants, err = db.GetProductVariants(ctx, 42)
+	require.NoError(t, err)
+	assert.Empty(t, variants)
+}
+
+func TestUpdateVariant(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testVariants := []Variant{
+		{ID: 1, ProductID: 1, SKU: "TS-S", Options: map[string]string{"size": "S"}},
+	}
+	err = PopulateTestData(ctx, db, "product_variants", testVariants)
+	require.NoError(t, err)
+
+	variant, err := db.UpdateVariant(ctx, 1, 1, ClientVariant{SKU: "TS-XS", Options: map[string]string{"size": "XS"}, Price: ptr(money.MustParse("17.99", "USD"))})
+	require.NoError(t, err)
+	assert.Equal(t, "TS-XS", variant.SKU)
+	assert.Equal(t, map[string]string{"size": "XS"}, variant.Options)
+	require.NotNil(t, variant.Price)
+	assert.Equal(t, money.MustParse("17.99", "USD"), *variant.Price)
+
+	// The variant belongs to product 1, not 2
+	_, err = db.UpdateVariant(ctx, 2, 1, ClientVariant{SKU: "HD-S"})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDeleteVariant(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testVariants := []Variant{
+		{ID: 1, ProductID: 1, SKU: "TS-S", Options: map[string]string{"size": "S"}},
+	}
+	err = PopulateTestData(ctx, db, "product_variants", testVariants)
+	require.NoError(t, err)
+
+	err = db.DeleteVariant(ctx, 1, 1)
+	require.NoError(t, err)
+
+	err = db.DeleteVariant(ctx, 1, 1)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPostReviewWithVariant(t *testing.T) {
+	db, cleanup, ctx := setupEmptyDB(t)
+	defer cleanup()
+
+	testProducts := []Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := PopulateTestData(ctx, db, "products", testProducts)
+	require.NoError(t, err)
+
+	testVariants := []Variant{
+		{ID: 1, ProductID: 1, SKU: "TS-S", Options: map[string]string{"size": "S"}},
+	}
+	err = PopulateTestData(ctx, db, "product_variants", testVariants)
+	require.NoError(t, err)
+
+	review, err := db.PostReview(ctx, ClientReview{ProductID: 1, VariantID: ptr(int64(1)), ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 4}, "1", ReviewApproved)
+	require.NoError(t, err)
+	require.NotNil(t, review.VariantID)
+	assert.Equal(t, int64(1), *review.VariantID)
+
+	reviews, _, err := db.GetProductReviews(ctx, 1, Page{})
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	require.NotNil(t, reviews[0].VariantID)
+	assert.Equal(t, int64(1), *reviews[0].VariantID)
+
+	// Variant 1 belongs to product 1, not 2
+	_, err = db.PostReview(ctx, ClientReview{ProductID: 2, VariantID: ptr(int64(1)), ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 4}, "1", ReviewApproved)
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,121 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// Register the decoders of the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Format is an encoding renditions are generated in
+type Format string
+
+const (
+	JPEG Format = "jpeg"
+	WebP Format = "webp"
+)
+
+// Formats are the encodings every rendition is generated in, WebP first
+// since clients should prefer it when they support it
+var Formats = []Format{WebP, JPEG}
+
+// Widths are the rendition widths in pixels, from thumbnail to full size
+var Widths = []int{150, 400, 1200}
+
+const (
+	// maxPixels guards against decompression bombs, a small file can
+	// declare dimensions that would take gigabytes to decode
+	maxPixels = 50_000_000
+	// jpegQuality is the quality JPEG renditions are encoded with
+	jpegQuality = 82
+)
+
+// ErrUnsupportedImage is returned when an image cannot be decoded or is too large to process
+var ErrUnsupportedImage = errors.New("unsupported image")
+
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+func (f Format) Extension() string {
+	if f == JPEG {
+		return ".jpg"
+	}
+	return "." + string(f)
+}
+
+// Decode reads a JPEG, PNG, GIF or WebP image
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrUnsupportedImage, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+	return img, nil
+}
+
+// Sizes returns the widths to generate for an image width pixels wide.
+// Widths above the original are skipped since upscaling adds nothing, the
+// original width is used instead, capped at the largest of Widths. This way
+// small images still get a rendition and the largest rendition of an image
+// between two widths is not far below the source.
+func Sizes(width int) []int {
+	var sizes []int
+	for _, w := range Widths {
+		if w <= width {
+			sizes = append(sizes, w)
+		}
+	}
+	largest := min(width, Widths[len(Widths)-1])
+	if len(sizes) == 0 || sizes[len(sizes)-1] < largest {
+		sizes = append(sizes, largest)
+	}
+	return sizes
+}
+
+// Resize scales src to the given width, keeping its aspect ratio
+func Resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
+
+// Encode writes img in the given format. JPEG has no transparency, so
+// transparent areas are flattened onto white.
+func Encode(w io.Writer, img image.Image, f Format) error {
+	switch f {
+	case JPEG:
+		bounds := img.Bounds()
+		flat := image.NewRGBA(bounds)
+		draw.Draw(flat, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: jpegQuality})
+	case WebP:
+		return nativewebp.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unknown image format %q", f)
+	}
+}
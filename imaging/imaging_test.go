@@ -0,0 +1,78 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+func TestSizes(t *testing.T) {
+	assert.Equal(t, []int{150, 400, 1200}, Sizes(4000))
+	assert.Equal(t, []int{150, 400, 1200}, Sizes(1200))
+	assert.Equal(t, []int{150, 400, 800}, Sizes(800))
+	assert.Equal(t, []int{150}, Sizes(150))
+	assert.Equal(t, []int{100}, Sizes(100))
+}
+
+func TestResizeAndEncode(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 600))
+	for x := 0; x < 800; x++ {
+		src.Set(x, x%600, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	img, err := Decode(buf.Bytes())
+	require.NoError(t, err)
+
+	resized := Resize(img, 400)
+	assert.Equal(t, image.Rect(0, 0, 400, 300), resized.Bounds())
+
+	for _, f := range Formats {
+		var out bytes.Buffer
+		require.NoError(t, Encode(&out, resized, f), f)
+
+		decoded, format, err := image.Decode(bytes.NewReader(out.Bytes()))
+		require.NoError(t, err, f)
+		assert.Equal(t, string(f), format)
+		assert.Equal(t, resized.Bounds(), decoded.Bounds())
+	}
+
+	// WebP keeps transparency
+	var out bytes.Buffer
+	require.NoError(t, Encode(&out, image.NewNRGBA(image.Rect(0, 0, 4, 4)), WebP))
+	decoded, err := webp.Decode(&out)
+	require.NoError(t, err)
+	_, _, _, a := decoded.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0), a)
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	_, err := Decode([]byte("not an image"))
+	require.ErrorIs(t, err, ErrUnsupportedImage)
+
+	// A PNG header declaring far more pixels than we are willing to decode
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 1<<14)
+	binary.BigEndian.PutUint32(data[20:], 1<<14)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	_, err = Decode(data)
+	require.ErrorIs(t, err, ErrUnsupportedImage)
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "image/jpeg", JPEG.ContentType())
+	assert.Equal(t, ".jpg", JPEG.Extension())
+	assert.Equal(t, "image/webp", WebP.ContentType())
+	assert.Equal(t, ".webp", WebP.Extension())
+}
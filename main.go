@@ -13,6 +13,7 @@ import (
 	"catalogapi/config"
 	"catalogapi/db"
 	"catalogapi/server"
+	"catalogapi/worker"
 )
 
 func main() {
@@ -44,10 +45,30 @@ func main() {
 		}
 	}()
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go worker.Every(workerCtx, "expired reservation cleanup", cfg.Inventory.SweepInterval, func(ctx context.Context) error {
+		_, err := database.DeleteExpiredReservations(ctx)
+		return err
+	})
+	go worker.Every(workerCtx, "scheduled price changes", cfg.Pricing.ScheduleInterval, func(ctx context.Context) error {
+		_, err := database.ApplyScheduledPriceChanges(ctx)
+		return err
+	})
+	go worker.Every(workerCtx, "image renditions", cfg.Storage.ProcessInterval, func(ctx context.Context) error {
+		_, err := srv.GenerateImageRenditions(ctx)
+		return err
+	})
+	go worker.Every(workerCtx, "related products", cfg.Related.RefreshInterval, func(ctx context.Context) error {
+		_, err := database.RefreshProductRelations(ctx)
+		return err
+	})
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -0,0 +1,136 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RoundingMode decides which way a converted amount that falls between two
+// multiples of the rounding increment goes
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds to the nearest multiple, halves away from zero
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundUp rounds towards positive infinity
+	RoundUp RoundingMode = "up"
+	// RoundDown rounds towards negative infinity
+	RoundDown RoundingMode = "down"
+)
+
+// Rounding is the rule applied to converted amounts. Increment is in minor
+// units of the target currency, so an increment of 10 in ILS rounds to the
+// nearest 10 agorot and an increment of 100 rounds to whole shekels.
+type Rounding struct {
+	Mode      RoundingMode
+	Increment int64
+}
+
+// DefaultRounding rounds to the nearest minor unit
+var DefaultRounding = Rounding{Mode: RoundHalfUp, Increment: 1}
+
+// Validate checks that the rounding rule can be applied
+func (r Rounding) Validate() error {
+	switch r.Mode {
+	case RoundHalfUp, RoundUp, RoundDown:
+	default:
+		return fmt.Errorf("rounding mode must be one of %s, %s or %s", RoundHalfUp, RoundUp, RoundDown)
+	}
+	if r.Increment < 1 {
+		return fmt.Errorf("rounding increment must be at least 1 minor unit")
+	}
+	return nil
+}
+
+// Exchange rates are stored as NUMERIC(18, 8)
+const (
+	rateDecimals    = 8
+	rateWholeDigits = 18 - rateDecimals
+)
+
+// ParseRate reads an exchange rate such as "3.6512". It must be a positive
+// plain decimal number with at most rateDecimals decimals, fractions and
+// exponents are rejected rather than rounded.
+func ParseRate(s string) (*big.Rat, error) {
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return nil, fmt.Errorf("rate %q must be a positive decimal number", s)
+	}
+	if len(frac) > rateDecimals {
+		return nil, fmt.Errorf("rate %q has more than %d decimals", s, rateDecimals)
+	}
+	if len(strings.TrimLeft(whole, "0")) > rateWholeDigits {
+		return nil, fmt.Errorf("rate %q is out of range", s)
+	}
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("rate %q must be a positive decimal number", s)
+	}
+	return rate, nil
+}
+
+// Convert multiplies m by rate, the number of units of the target currency
+// per unit of m's currency, and rounds the result with r. The arithmetic is
+// exact up to the final rounding.
+func (m Money) Convert(rate *big.Rat, to string, r Rounding) (Money, error) {
+	if err := ValidateCurrency(to); err != nil {
+		return Money{}, err
+	}
+	if err := r.Validate(); err != nil {
+		return Money{}, err
+	}
+
+	// minor units of the target = minor units of m * rate * 10^(to exp - from exp)
+	target := new(big.Rat).Mul(new(big.Rat).SetInt64(m.amount), rate)
+	shift := Exponent(to) - Exponent(m.Currency())
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(absInt(shift))), nil))
+	if shift >= 0 {
+		target.Mul(target, scale)
+	} else {
+		target.Quo(target, scale)
+	}
+
+	steps := roundRat(target.Quo(target, new(big.Rat).SetInt64(r.Increment)), r.Mode)
+	minor := steps.Mul(steps, big.NewInt(r.Increment))
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%w: converted amount is out of range", ErrInvalidAmount)
+	}
+	return Money{amount: minor.Int64(), currency: to}, nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// roundRat rounds x to an integer following mode
+func roundRat(x *big.Rat, mode RoundingMode) *big.Int {
+	// Euclidean division gives floor for a positive denominator
+	q, rem := new(big.Int).DivMod(x.Num(), x.Denom(), new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+
+	switch mode {
+	case RoundUp:
+		return q.Add(q, big.NewInt(1))
+	case RoundDown:
+		return q
+	}
+
+	// Half up: compare twice the remainder with the denominator, a positive
+	// x rounds up from the half and a negative one rounds down, away from zero
+	twice := new(big.Int).Lsh(rem, 1)
+	switch c := twice.Cmp(x.Denom()); {
+	case c > 0, c == 0 && x.Sign() > 0:
+		return q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		rate     string
+		to       string
+		rounding Rounding
+		expected Money
+	}{
+		{amount: MustParse("19.99", "USD"), rate: "0.92", to: "EUR", rounding: DefaultRounding, expected: MustParse("18.39", "EUR")},
+		{amount: MustParse("10.00", "USD"), rate: "0.925", to: "EUR", rounding: DefaultRounding, expected: MustParse("9.25", "EUR")},
+		{amount: MustParse("0.05", "USD"), rate: "0.5", to: "EUR", rounding: DefaultRounding, expected: MustParse("0.03", "EUR")},
+		{amount: MustParse("0.05", "USD"), rate: "0.5", to: "EUR", rounding: Rounding{Mode: RoundDown, Increment: 1}, expected: MustParse("0.02", "EUR")},
+		{amount: MustParse("19.99", "USD"), rate: "3.61", to: "ILS", rounding: Rounding{Mode: RoundHalfUp, Increment: 10}, expected: MustParse("72.20", "ILS")},
+		{amount: MustParse("19.99", "USD"), rate: "3.61", to: "ILS", rounding: Rounding{Mode: RoundUp, Increment: 100}, expected: MustParse("73", "ILS")},
+		{amount: MustParse("19.99", "USD"), rate: "151.2", to: "JPY", rounding: DefaultRounding, expected: MustParse("3022", "JPY")},
+		{amount: MustParse("1500", "JPY"), rate: "0.0066", to: "USD", rounding: DefaultRounding, expected: MustParse("9.90", "USD")},
+		{amount: MustParse("-0.05", "USD"), rate: "0.5", to: "EUR", rounding: DefaultRounding, expected: MustParse("-0.03", "EUR")},
+	}
+
+	for _, tt := range tests {
+		rate, err := ParseRate(tt.rate)
+		require.NoError(t, err)
+		converted, err := tt.amount.Convert(rate, tt.to, tt.rounding)
+		require.NoError(t, err, tt.amount.String())
+		assert.Equal(t, tt.expected, converted, "%s at %s", tt.amount, tt.rate)
+	}
+}
+
+func TestConvertInvalid(t *testing.T) {
+	rate, err := ParseRate("1.1")
+	require.NoError(t, err)
+
+	_, err = MustParse("1", "USD").Convert(rate, "eur", DefaultRounding)
+	assert.Error(t, err)
+
+	_, err = MustParse("1", "USD").Convert(rate, "EUR", Rounding{Mode: "sideways", Increment: 1})
+	assert.Error(t, err)
+
+	_, err = MustParse("1", "USD").Convert(rate, "EUR", Rounding{Mode: RoundUp})
+	assert.Error(t, err)
+
+	for _, s := range []string{"0", "-1.5", "abc", "", "1/3", "1e3", "+1", ".5", "1.", "0.000000001", "12345678901"} {
+		_, err := ParseRate(s)
+		assert.Error(t, err, s)
+	}
+
+	for _, s := range []string{"3.61000000", "0.00000001", "1234567890.12345678", "007"} {
+		_, err := ParseRate(s)
+		assert.NoError(t, err, s)
+	}
+}
@@ -0,0 +1,298 @@
+// Package money represents monetary amounts exactly, as an integer number of
+// minor units (such as cents) together with an ISO 4217 currency code.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultCurrency is the currency catalog prices are stored in
+const DefaultCurrency = "USD"
+
+var (
+	// ErrCurrencyMismatch is returned when combining amounts of different currencies
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrInvalidAmount is returned when an amount cannot be represented exactly
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// exponents lists the ISO 4217 currencies whose minor unit is not a hundredth
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// Money is an exact amount in a single currency. The zero value is zero in
+// DefaultCurrency.
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// New returns an amount of minor units, New(1999, "USD") is $19.99
+func New(minor int64, currency string) Money {
+	return Money{amount: minor, currency: currency}
+}
+
+// Parse reads a decimal amount such as "19.99" in the given currency. It
+// fails rather than rounds when the amount has more decimals than the
+// currency's minor unit.
+func Parse(amount, currency string) (Money, error) {
+	if err := ValidateCurrency(currency); err != nil {
+		return Money{}, err
+	}
+	exp := Exponent(currency)
+
+	s := amount
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+
+	minor, err := strconv.ParseInt(whole+frac+strings.Repeat("0", exp-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{amount: minor, currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on error, it is meant for constants and tests
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// ValidateCurrency checks that code looks like an ISO 4217 alphabetic code
+func ValidateCurrency(code string) error {
+	if len(code) != 3 {
+		return fmt.Errorf("currency %q must be a three letter ISO 4217 code", code)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return fmt.Errorf("currency %q must be a three letter ISO 4217 code", code)
+		}
+	}
+	return nil
+}
+
+// Exponent returns the number of decimals of a currency's minor unit
+func Exponent(currency string) int {
+	if exp, ok := exponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Minor returns the amount in minor units
+func (m Money) Minor() int64 {
+	return m.amount
+}
+
+// Currency returns the ISO 4217 code of the amount
+func (m Money) Currency() string {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+// Add returns m + o, both amounts must share a currency
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency() != o.Currency() {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency(), o.Currency())
+	}
+	return Money{amount: m.amount + o.amount, currency: m.Currency()}, nil
+}
+
+// Sub returns m - o, both amounts must share a currency
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency() != o.Currency() {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency(), o.Currency())
+	}
+	return Money{amount: m.amount - o.amount, currency: m.Currency()}, nil
+}
+
+// Mul returns m multiplied by a quantity
+func (m Money) Mul(n int64) Money {
+	return Money{amount: m.amount * n, currency: m.Currency()}
+}
+
+// Equal reports whether m and o are the same amount in the same currency,
+// an empty currency counting as DefaultCurrency. Use it instead of ==.
+func (m Money) Equal(o Money) bool {
+	return m.amount == o.amount && m.Currency() == o.Currency()
+}
+
+// Cmp compares two amounts of the same currency and returns -1, 0 or +1
+func (m Money) Cmp(o Money) (int, error) {
+	if m.Currency() != o.Currency() {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency(), o.Currency())
+	}
+	switch {
+	case m.amount < o.amount:
+		return -1, nil
+	case m.amount > o.amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Decimal formats the amount without its currency, such as "19.99"
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency())
+	digits := strconv.FormatUint(absUint(m.amount), 10)
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+
+	var b strings.Builder
+	if m.amount < 0 {
+		b.WriteByte('-')
+	}
+	b.WriteString(digits[:len(digits)-exp])
+	if exp > 0 {
+		b.WriteByte('.')
+		b.WriteString(digits[len(digits)-exp:])
+	}
+	return b.String()
+}
+
+// String formats the amount with its currency, such as "19.99 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency()
+}
+
+// jsonMoney is the wire format of Money, the amount is a string so that
+// clients never parse it into a binary float
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount, _ := json.Marshal(m.Decimal())
+	return json.Marshal(jsonMoney{Amount: amount, Currency: m.Currency()})
+}
+
+// UnmarshalJSON accepts {"amount": "19.99", "currency": "USD"}. The amount
+// may also be a JSON number, which is read from its literal text and never
+// goes through a float. A missing currency means DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v jsonMoney
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("money must be an object with an amount and a currency: %w", err)
+	}
+	if len(v.Amount) == 0 {
+		return fmt.Errorf("%w: amount is required", ErrInvalidAmount)
+	}
+
+	amount := string(v.Amount)
+	if bytes.HasPrefix(v.Amount, []byte(`"`)) {
+		if err := json.Unmarshal(v.Amount, &amount); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidAmount, err)
+		}
+	}
+	if v.Currency == "" {
+		v.Currency = DefaultCurrency
+	}
+
+	parsed, err := Parse(amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ScanNumeric lets pgx scan a numeric column into Money. The column carries
+// no currency, so the amount is read in DefaultCurrency unless m already
+// holds a currency.
+func (m *Money) ScanNumeric(n pgtype.Numeric) error {
+	if !n.Valid {
+		return fmt.Errorf("cannot scan NULL into money.Money")
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("%w: cannot scan a non-finite numeric into money.Money", ErrInvalidAmount)
+	}
+
+	currency := m.Currency()
+	minor := new(big.Int).Set(n.Int)
+	shift := int64(n.Exp) + int64(Exponent(currency))
+	if shift >= 0 {
+		minor.Mul(minor, new(big.Int).Exp(big.NewInt(10), big.NewInt(shift), nil))
+	} else {
+		var rem big.Int
+		minor.QuoRem(minor, new(big.Int).Exp(big.NewInt(10), big.NewInt(-shift), nil), &rem)
+		if rem.Sign() != 0 {
+			return fmt.Errorf("%w: numeric has more decimals than %s allows", ErrInvalidAmount, currency)
+		}
+	}
+	if !minor.IsInt64() {
+		return fmt.Errorf("%w: numeric is out of range", ErrInvalidAmount)
+	}
+
+	*m = Money{amount: minor.Int64(), currency: currency}
+	return nil
+}
+
+// NumericValue lets pgx write Money into a numeric column
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{
+		Int:   big.NewInt(m.amount),
+		Exp:   int32(-Exponent(m.Currency())),
+		Valid: true,
+	}, nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func absUint(n int64) uint64 {
+	if n == math.MinInt64 {
+		return uint64(math.MaxInt64) + 1
+	}
+	if n < 0 {
+		return uint64(-n)
+	}
+	return uint64(n)
+}
@@ -0,0 +1,152 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		minor    int64
+		decimal  string
+	}{
+		{amount: "19.99", currency: "USD", minor: 1999, decimal: "19.99"},
+		{amount: "19.9", currency: "USD", minor: 1990, decimal: "19.90"},
+		{amount: "7", currency: "EUR", minor: 700, decimal: "7.00"},
+		{amount: "0.05", currency: "ILS", minor: 5, decimal: "0.05"},
+		{amount: "-3.50", currency: "USD", minor: -350, decimal: "-3.50"},
+		{amount: "1500", currency: "JPY", minor: 1500, decimal: "1500"},
+		{amount: "1.234", currency: "KWD", minor: 1234, decimal: "1.234"},
+	}
+
+	for _, tt := range tests {
+		m, err := Parse(tt.amount, tt.currency)
+		require.NoError(t, err, tt.amount)
+		assert.Equal(t, tt.minor, m.Minor(), tt.amount)
+		assert.Equal(t, tt.currency, m.Currency(), tt.amount)
+		assert.Equal(t, tt.decimal, m.Decimal(), tt.amount)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+	}{
+		{amount: "19.999", currency: "USD"},
+		{amount: "1.5", currency: "JPY"},
+		{amount: "", currency: "USD"},
+		{amount: "1.", currency: "USD"},
+		{amount: ".5", currency: "USD"},
+		{amount: "1e3", currency: "USD"},
+		{amount: "+1", currency: "USD"},
+		{amount: "99999999999999999999", currency: "USD"},
+		{amount: "1.00", currency: "usd"},
+		{amount: "1.00", currency: "DOLLAR"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.amount, tt.currency)
+		assert.Error(t, err, tt.amount+" "+tt.currency)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	// 0.1 + 0.2 is exactly 0.3, unlike with float64
+	sum, err := MustParse("0.10", "USD").Add(MustParse("0.20", "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, MustParse("0.30", "USD"), sum)
+
+	diff, err := MustParse("5.00", "EUR").Sub(MustParse("7.25", "EUR"))
+	require.NoError(t, err)
+	assert.Equal(t, "-2.25", diff.Decimal())
+	assert.True(t, diff.IsNegative())
+
+	assert.Equal(t, "59.97", MustParse("19.99", "USD").Mul(3).Decimal())
+
+	_, err = MustParse("1", "USD").Add(MustParse("1", "EUR"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	cmp, err := MustParse("1", "USD").Cmp(MustParse("0.99", "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, cmp)
+}
+
+func TestZeroValue(t *testing.T) {
+	var m Money
+	assert.True(t, m.IsZero())
+	assert.Equal(t, DefaultCurrency, m.Currency())
+	assert.Equal(t, "0.00", m.Decimal())
+	assert.Equal(t, "0.00 USD", m.String())
+	assert.True(t, m.Equal(MustParse("0", DefaultCurrency)))
+	assert.False(t, m.Equal(MustParse("0", "EUR")))
+	assert.False(t, MustParse("1", "USD").Equal(MustParse("1.01", "USD")))
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(MustParse("19.99", "USD"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount": "19.99", "currency": "USD"}`, string(data))
+
+	tests := []struct {
+		body     string
+		expected Money
+	}{
+		{body: `{"amount": "19.99", "currency": "USD"}`, expected: New(1999, "USD")},
+		{body: `{"amount": 19.99, "currency": "EUR"}`, expected: New(1999, "EUR")},
+		{body: `{"amount": "5"}`, expected: New(500, DefaultCurrency)},
+	}
+	for _, tt := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(tt.body), &m)
+		require.NoError(t, err, tt.body)
+		assert.Equal(t, tt.expected, m, tt.body)
+	}
+
+	for _, body := range []string{`19.99`, `{"currency": "USD"}`, `{"amount": "1.999"}`, `{"amount": true}`} {
+		var m Money
+		err := json.Unmarshal([]byte(body), &m)
+		assert.Error(t, err, body)
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	n, err := MustParse("19.99", "USD").NumericValue()
+	require.NoError(t, err)
+	assert.Equal(t, pgtype.Numeric{Int: big.NewInt(1999), Exp: -2, Valid: true}, n)
+
+	tests := []struct {
+		numeric  pgtype.Numeric
+		expected Money
+	}{
+		{numeric: pgtype.Numeric{Int: big.NewInt(1999), Exp: -2, Valid: true}, expected: New(1999, "USD")},
+		{numeric: pgtype.Numeric{Int: big.NewInt(19990), Exp: -3, Valid: true}, expected: New(1999, "USD")},
+		{numeric: pgtype.Numeric{Int: big.NewInt(2), Exp: 1, Valid: true}, expected: New(2000, "USD")},
+	}
+	for _, tt := range tests {
+		var m Money
+		err := m.ScanNumeric(tt.numeric)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, m)
+	}
+
+	var m Money
+	err = m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(19999), Exp: -3, Valid: true})
+	require.ErrorIs(t, err, ErrInvalidAmount)
+
+	err = m.ScanNumeric(pgtype.Numeric{})
+	require.Error(t, err)
+
+	// An amount scanned into a Money that already has a currency keeps it
+	jpy := New(0, "JPY")
+	err = jpy.ScanNumeric(pgtype.Numeric{Int: big.NewInt(1500), Exp: 0, Valid: true})
+	require.NoError(t, err)
+	assert.Equal(t, New(1500, "JPY"), jpy)
+}
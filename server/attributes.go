@@ -0,0 +1,91 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// attrParamPrefix starts the query parameters filtering on an attribute,
+	// as in attr.brand=Acme
+	attrParamPrefix = "attr."
+	// maxAttributes caps the attributes a single product can carry
+	maxAttributes = 50
+	// maxAttributeValueLength caps the length of an attribute value
+	maxAttributeValueLength = 255
+)
+
+// attributeNamePattern matches the attribute names accepted on products
+var attributeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// getProductFacets returns the attribute values of the products matching
+// the listing filters along with their counts, for building filter sidebars
+func (s *Server) getProductFacets(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseProductFilter(r, productFacetParams)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	facets, err := s.db.GetProductFacets(r.Context(), filter)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listResponse[db.Facet]{Items: facets})
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// parseAttributeFilters reads the attr.<name> query parameters. Repeating a
+// parameter matches any of its values.
+func parseAttributeFilters(query url.Values) (map[string][]string, error) {
+	var attributes map[string][]string
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, attrParamPrefix)
+		if !ok {
+			continue
+		}
+		if !attributeNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid attribute filter %q, attribute names are 1-64 lowercase letters, digits, '_' or '-'", key)
+		}
+		for _, value := range values {
+			if strings.TrimSpace(value) == "" {
+				return nil, fmt.Errorf("%s must not be empty", key)
+			}
+		}
+		if attributes == nil {
+			attributes = make(map[string][]string)
+		}
+		attributes[name] = values
+	}
+	return attributes, nil
+}
+
+// attributes checks the attributes of a product, a bad attribute is reported
+// under its own name so the client can tell which one it is
+func (v *validator) attributes(field string, attributes db.Attributes) {
+	v.check(len(attributes) <= maxAttributes, field, fmt.Sprintf("must hold at most %d attributes", maxAttributes))
+	for _, name := range slices.Sorted(maps.Keys(attributes)) {
+		value := attributes[name]
+		attrField := field + "." + name
+		if !attributeNamePattern.MatchString(name) {
+			v.check(false, attrField, "is not a valid attribute name, names are 1-64 lowercase letters, digits, '_' or '-'")
+			continue
+		}
+		v.required(attrField, value)
+		v.check(len(value) <= maxAttributeValueLength, attrField, fmt.Sprintf("must be at most %d characters", maxAttributeValueLength))
+	}
+}
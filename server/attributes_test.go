@@ -0,0 +1,81 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/money"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProductFacets(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Attributes: db.Attributes{"brand": "Acme", "material": "cotton"}},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Attributes: db.Attributes{"brand": "Globex", "material": "cotton"}},
+		{ID: 3, Name: "Jacket", Price: money.MustParse("99.99", "USD"), Image: "https://via.placeholder.com/150", Attributes: db.Attributes{"brand": "Acme", "material": "leather"}},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/facets?max_price=50", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res listResponse[db.Facet]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	assert.Equal(t, []db.Facet{
+		{Attribute: "brand", Values: []db.FacetValue{{Value: "Acme", Count: 1}, {Value: "Globex", Count: 1}}},
+		{Attribute: "material", Values: []db.FacetValue{{Value: "cotton", Count: 2}}},
+	}, res.Items)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products?attr.brand=Acme", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var products listResponse[db.Product]
+	err = json.NewDecoder(w.Body).Decode(&products)
+	require.NoError(t, err)
+	require.Len(t, products.Items, 2)
+	assert.Equal(t, "leather", products.Items[1].Attributes["material"])
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products/facets?sort=price", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestValidateAttributes(t *testing.T) {
+	validate := func(attributes db.Attributes) error {
+		var v validator
+		v.attributes("attributes", attributes)
+		return v.err()
+	}
+	require.NoError(t, validate(nil))
+	require.NoError(t, validate(db.Attributes{"brand": "Acme", "weight_g": "250"}))
+
+	for _, tt := range []struct {
+		attributes db.Attributes
+		field      string
+	}{
+		{attributes: db.Attributes{"Brand": "Acme"}, field: "attributes.Brand"},
+		{attributes: db.Attributes{"brand": " "}, field: "attributes.brand"},
+		{attributes: db.Attributes{"brand": strings.Repeat("a", maxAttributeValueLength+1)}, field: "attributes.brand"},
+	} {
+		var verr *validationError
+		require.ErrorAs(t, validate(tt.attributes), &verr, tt.attributes)
+		require.Len(t, verr.Errors, 1)
+		assert.Equal(t, tt.field, verr.Errors[0].Field)
+	}
+}
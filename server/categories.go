@@ -0,0 +1,151 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// slugPattern matches lowercase, dash separated URL slugs
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// productCategoriesRequest is the payload of PUT /api/products/{id}/categories
+type productCategoriesRequest struct {
+	CategoryIDs []int64 `json:"category_ids"`
+}
+
+func (s *Server) getCategoryTree(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.db.GetCategoryTree(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if categories == nil {
+		categories = []db.Category{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(categories)
+}
+
+func (s *Server) getCategoryProducts(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := parseProductFilter(r, categoryProductListParams)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.CategoryID = &id
+
+	if _, err := s.db.GetCategory(r.Context(), id); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	s.writeProductList(w, r, filter)
+}
+
+func (s *Server) createCategory(w http.ResponseWriter, r *http.Request) {
+	var clientCategory db.ClientCategory
+	if err := decodeJSON(r, &clientCategory); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientCategory(clientCategory); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	category, err := s.db.CreateCategory(r.Context(), clientCategory)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	category.Children = []db.Category{}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+func (s *Server) updateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var clientCategory db.ClientCategory
+	if err := decodeJSON(r, &clientCategory); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientCategory(clientCategory); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	category, err := s.db.UpdateCategory(r.Context(), id, clientCategory)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	category.Children = []db.Category{}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(category)
+}
+
+func (s *Server) deleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteCategory(r.Context(), id); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) setProductCategories(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req productCategoriesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	if err := s.db.SetProductCategories(r.Context(), id, req.CategoryIDs); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func validateClientCategory(c db.ClientCategory) error {
+	var v validator
+	v.required("name", c.Name)
+	v.check(slugPattern.MatchString(c.Slug), "slug", "must be lowercase letters, digits and single dashes")
+	return v.err()
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCategoryTree(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	parentID := int64(1)
+	testCategories := []db.Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+		{ID: 2, ParentID: &parentID, Name: "Desks", Slug: "desks"},
+	}
+	err := db.PopulateTestData(ctx, database, "categories", testCategories)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var tree []db.Category
+	err = json.NewDecoder(w.Body).Decode(&tree)
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	assert.Equal(t, "furniture", tree[0].Slug)
+	require.Len(t, tree[0].Children, 1)
+	assert.Equal(t, "desks", tree[0].Children[0].Slug)
+}
+
+func TestGetCategoryProducts(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	parentID := int64(1)
+	testCategories := []db.Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+		{ID: 2, ParentID: &parentID, Name: "Desks", Slug: "desks"},
+	}
+	err = db.PopulateTestData(ctx, database, "categories", testCategories)
+	require.NoError(t, err)
+	require.NoError(t, database.SetProductCategories(ctx, 1, []int64{2}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/categories/1/products", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res listResponse[db.Product]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.Len(t, res.Items, 1)
+	validateProduct(t, res.Items[0], testProducts[0])
+
+	r = httptest.NewRequest(http.MethodGet, "/api/categories/42/products", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	// The category comes from the path only
+	r = httptest.NewRequest(http.MethodGet, "/api/categories/1/products?category=2", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateCategory(t *testing.T) {
+	database, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	tests := []struct {
+		category db.ClientCategory
+		expected int
+	}{
+		{category: db.ClientCategory{Name: "Furniture", Slug: "furniture"}, expected: http.StatusCreated},
+		{category: db.ClientCategory{Name: "Duplicate", Slug: "furniture"}, expected: http.StatusConflict},
+		{category: db.ClientCategory{Name: "", Slug: "empty"}, expected: http.StatusUnprocessableEntity},
+		{category: db.ClientCategory{Name: "Bad Slug", Slug: "Bad Slug"}, expected: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		jsonData, err := json.Marshal(tt.category)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/api/categories", bytes.NewBuffer(jsonData))
+		w := httptest.NewRecorder()
+		srv.createCategory(w, r)
+		require.Equal(t, tt.expected, w.Code, tt.category.Slug)
+	}
+}
+
+func TestUpdateCategory(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	parentID := int64(1)
+	testCategories := []db.Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+		{ID: 2, ParentID: &parentID, Name: "Desks", Slug: "desks"},
+	}
+	err := db.PopulateTestData(ctx, database, "categories", testCategories)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/categories/1", bytes.NewBufferString(`{"parent_id": 2, "name": "Furniture", "slug": "furniture"}`))
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	srv.updateCategory(w, r)
+	require.Equal(t, http.StatusConflict, w.Code)
+
+	r = httptest.NewRequest(http.MethodPut, "/api/categories/2", bytes.NewBufferString(`{"name": "All Desks", "slug": "all-desks"}`))
+	r.SetPathValue("id", "2")
+	w = httptest.NewRecorder()
+	srv.updateCategory(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var category db.Category
+	err = json.NewDecoder(w.Body).Decode(&category)
+	require.NoError(t, err)
+	assert.Equal(t, "all-desks", category.Slug)
+	assert.Nil(t, category.ParentID)
+}
+
+func TestDeleteCategory(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testCategories := []db.Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+	}
+	err := db.PopulateTestData(ctx, database, "categories", testCategories)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/categories/1", nil)
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	srv.deleteCategory(w, r)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.deleteCategory(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSetProductCategories(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+	testCategories := []db.Category{
+		{ID: 1, Name: "Furniture", Slug: "furniture"},
+	}
+	err = db.PopulateTestData(ctx, database, "categories", testCategories)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/products/1/categories", bytes.NewBufferString(`{"category_ids": [1]}`))
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	srv.setProductCategories(w, r)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	r = httptest.NewRequest(http.MethodPut, "/api/products/1/categories", bytes.NewBufferString(`{"category_ids": [42]}`))
+	r.SetPathValue("id", "1")
+	w = httptest.NewRecorder()
+	srv.setProductCategories(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/money"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// parseCurrency reads the currency query parameter, prices are returned in
+// the base currency when it is missing
+func parseCurrency(r *http.Request) (string, error) {
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		return money.DefaultCurrency, nil
+	}
+	if err := money.ValidateCurrency(currency); err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
+func (s *Server) getExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := s.db.GetExchangeRates(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if rates == nil {
+		rates = []db.ExchangeRate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rates)
+}
+
+func (s *Server) setExchangeRate(w http.ResponseWriter, r *http.Request) {
+	currency := r.PathValue("currency")
+	if err := money.ValidateCurrency(currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if currency == money.DefaultCurrency {
+		http.Error(w, fmt.Sprintf("%s is the base currency and has no exchange rate", currency), http.StatusBadRequest)
+		return
+	}
+
+	var clientRate db.ClientExchangeRate
+	if err := decodeJSON(r, &clientRate); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if clientRate.RoundingMode == "" {
+		clientRate.RoundingMode = money.DefaultRounding.Mode
+	}
+	if clientRate.RoundingIncrement == 0 {
+		clientRate.RoundingIncrement = money.DefaultRounding.Increment
+	}
+	if err := validateClientExchangeRate(clientRate); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	rate, err := s.db.SetExchangeRate(r.Context(), currency, clientRate)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rate)
+}
+
+func (s *Server) deleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	currency := r.PathValue("currency")
+	if err := money.ValidateCurrency(currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteExchangeRate(r.Context(), currency); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getProductPrices(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.GetProduct(r.Context(), productID); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	prices, err := s.db.GetProductPrices(r.Context(), productID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(prices)
+}
+
+func (s *Server) setProductPrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var price money.Money
+	if err := decodeJSON(r, &price); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateProductPrice(price); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	if err := s.db.SetProductPrice(r.Context(), productID, price); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(price)
+}
+
+func (s *Server) deleteProductPrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	currency := r.PathValue("currency")
+	if err := money.ValidateCurrency(currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteProductPrice(r.Context(), productID, currency); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// validateProductPrice checks a price set in a currency other than the base
+// one, the body is the price itself
+func validateProductPrice(price money.Money) error {
+	var v validator
+	v.check(!price.IsNegative(), "amount", "must not be negative")
+	v.check(price.Currency() != money.DefaultCurrency, "currency",
+		fmt.Sprintf("must not be %s, the base currency, update the product price instead", money.DefaultCurrency))
+	return v.err()
+}
+
+func validateClientExchangeRate(r db.ClientExchangeRate) error {
+	if _, err := money.ParseRate(r.Rate); err != nil {
+		return err
+	}
+	return money.Rounding{Mode: r.RoundingMode, Increment: r.RoundingIncrement}.Validate()
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetExchangeRate(t *testing.T) {
+	database, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	tests := []struct {
+		currency string
+		body     string
+		expected int
+	}{
+		{currency: "EUR", body: `{"rate": "0.92"}`, expected: http.StatusOK},
+		{currency: "ILS", body: `{"rate": "3.61", "rounding_mode": "up", "rounding_increment": 10}`, expected: http.StatusOK},
+		{currency: "USD", body: `{"rate": "1"}`, expected: http.StatusBadRequest},
+		{currency: "eur", body: `{"rate": "0.92"}`, expected: http.StatusBadRequest},
+		{currency: "EUR", body: `{"rate": "-1"}`, expected: http.StatusBadRequest},
+		{currency: "EUR", body: `{"rate": "1/3"}`, expected: http.StatusBadRequest},
+		{currency: "EUR", body: `{"rate": "1e3"}`, expected: http.StatusBadRequest},
+		{currency: "EUR", body: `{"rate": "0.123456789"}`, expected: http.StatusBadRequest},
+		{currency: "EUR", body: `{"rate": "0.92", "rounding_mode": "sideways"}`, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/api/exchange-rates/"+tt.currency, bytes.NewBufferString(tt.body))
+		r.SetPathValue("currency", tt.currency)
+		w := httptest.NewRecorder()
+		srv.setExchangeRate(w, r)
+		require.Equal(t, tt.expected, w.Code, tt.currency+" "+tt.body)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/exchange-rates", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var rates []db.ExchangeRate
+	err := json.NewDecoder(w.Body).Decode(&rates)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	assert.Equal(t, money.RoundHalfUp, rates[0].RoundingMode)
+	assert.Equal(t, money.RoundUp, rates[1].RoundingMode)
+}
+
+func TestGetProductsInCurrency(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	_, err = database.SetExchangeRate(ctx, "EUR", db.ClientExchangeRate{Rate: "0.92", RoundingMode: money.RoundHalfUp, RoundingIncrement: 1})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/products/2/prices", bytes.NewBufferString(`{"amount": "44.90", "currency": "EUR"}`))
+	r.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+	srv.setProductPrice(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products?currency=EUR", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response listResponse[db.Product]
+	err = json.NewDecoder(w.Body).Decode(&response)
+	require.NoError(t, err)
+	require.Len(t, response.Items, 2)
+	assert.Equal(t, money.MustParse("18.39", "EUR"), response.Items[0].Price)
+	assert.Equal(t, money.MustParse("44.90", "EUR"), response.Items[1].Price)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products/1?currency=EUR", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("18.39", "EUR"), product.Price)
+
+	for _, query := range []string{"currency=GBP", "currency=euro"} {
+		r = httptest.NewRequest(http.MethodGet, "/api/products?"+query, nil)
+		w = httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestSetProductPrice(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	tests := []struct {
+		id       string
+		body     string
+		expected int
+	}{
+		{id: "1", body: `{"amount": "17.50", "currency": "EUR"}`, expected: http.StatusOK},
+		{id: "1", body: `{"amount": "17.50", "currency": "USD"}`, expected: http.StatusUnprocessableEntity},
+		{id: "1", body: `{"amount": "-1", "currency": "EUR"}`, expected: http.StatusUnprocessableEntity},
+		{id: "1", body: `{"amount": "1.5", "currency": "JPY"}`, expected: http.StatusBadRequest},
+		{id: "42", body: `{"amount": "17.50", "currency": "EUR"}`, expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/api/products/"+tt.id+"/prices", bytes.NewBufferString(tt.body))
+		r.SetPathValue("id", tt.id)
+		w := httptest.NewRecorder()
+		srv.setProductPrice(w, r)
+		require.Equal(t, tt.expected, w.Code, tt.body)
+	}
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/products/1/prices/EUR", nil)
+	r.SetPathValue("id", "1")
+	r.SetPathValue("currency", "EUR")
+	w := httptest.NewRecorder()
+	srv.deleteProductPrice(w, r)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	prices, err := database.GetProductPrices(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, prices)
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/money"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// productListParams are the query parameters accepted by GET /api/products.
+// Price filters and sorting always use the base currency price, currency only
+// changes how the returned prices are expressed. Likewise locale only changes
+// the language of the returned names and descriptions.
+var productListParams = []string{"limit", "cursor", "min_price", "max_price", "q", "created_after", "category", "sort", "currency", "locale", attrParamPrefix + "*"}
+
+// adminProductListParams are the query parameters accepted by GET
+// /api/admin/products, which can also list archived products
+var adminProductListParams = []string{"limit", "cursor", "min_price", "max_price", "q", "created_after", "category", "sort", "currency", "locale", attrParamPrefix + "*", "include_archived"}
+
+// categoryProductListParams are the query parameters accepted by GET
+// /api/categories/{id}/products. The category comes from the path, so a
+// category parameter is rejected rather than silently overridden.
+var categoryProductListParams = []string{"limit", "cursor", "min_price", "max_price", "q", "created_after", "sort", "currency", "locale", attrParamPrefix + "*"}
+
+// productFacetParams are the query parameters accepted by GET /api/products/facets
+var productFacetParams = []string{"min_price", "max_price", "q", "created_after", "category", attrParamPrefix + "*"}
+
+// checkQueryParams rejects any query parameter that is not in allowed. An
+// allowed entry ending with * accepts every parameter starting with it.
+func checkQueryParams(r *http.Request, allowed []string) error {
+	for key := range r.URL.Query() {
+		if !slices.ContainsFunc(allowed, func(a string) bool { return matchParam(a, key) }) {
+			return fmt.Errorf("unknown query parameter %q, allowed parameters are: %s", key, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// parseProductFilter reads the filtering and sorting query parameters of a
+// product listing, rejecting any parameter that is not in params
+func parseProductFilter(r *http.Request, params []string) (db.ProductFilter, error) {
+	if err := checkQueryParams(r, params); err != nil {
+		return db.ProductFilter{}, err
+	}
+
+	query := r.URL.Query()
+	var filter db.ProductFilter
+
+	if v := query.Get("min_price"); v != "" {
+		price, err := parsePrice("min_price", v)
+		if err != nil {
+			return db.ProductFilter{}, err
+		}
+		filter.MinPrice = &price
+	}
+	if v := query.Get("max_price"); v != "" {
+		price, err := parsePrice("max_price", v)
+		if err != nil {
+			return db.ProductFilter{}, err
+		}
+		filter.MaxPrice = &price
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && filter.MinPrice.Minor() > filter.MaxPrice.Minor() {
+		return db.ProductFilter{}, fmt.Errorf("min_price must not be greater than max_price")
+	}
+
+	filter.Query = strings.TrimSpace(query.Get("q"))
+
+	if v := query.Get("created_after"); v != "" {
+		createdAfter, err := parseTime(v)
+		if err != nil {
+			return db.ProductFilter{}, fmt.Errorf("created_after must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		filter.CreatedAfter = &createdAfter
+	}
+
+	if v := query.Get("category"); v != "" {
+		categoryID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return db.ProductFilter{}, fmt.Errorf("category must be a category id")
+		}
+		filter.CategoryID = &categoryID
+	}
+
+	if v := query.Get("sort"); v != "" {
+		sort, err := parseSort(v, db.ProductSortFields())
+		if err != nil {
+			return db.ProductFilter{}, err
+		}
+		filter.Sort = sort
+	}
+
+	attributes, err := parseAttributeFilters(query)
+	if err != nil {
+		return db.ProductFilter{}, err
+	}
+	filter.Attributes = attributes
+
+	if v := query.Get("include_archived"); v != "" {
+		includeArchived, err := strconv.ParseBool(v)
+		if err != nil {
+			return db.ProductFilter{}, fmt.Errorf("include_archived must be true or false")
+		}
+		filter.IncludeArchived = includeArchived
+	}
+
+	return filter, nil
+}
+
+func matchParam(allowed, key string) bool {
+	if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+		return strings.HasPrefix(key, prefix) && len(key) > len(prefix)
+	}
+	return allowed == key
+}
+
+func parsePrice(name, v string) (money.Money, error) {
+	price, err := money.Parse(v, money.DefaultCurrency)
+	if err != nil || price.IsNegative() {
+		return money.Money{}, fmt.Errorf("%s must be a non-negative amount with at most %d decimals", name, money.Exponent(money.DefaultCurrency))
+	}
+	return price, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
+// parseSort parses a comma separated sort spec such as "price,-created_at",
+// a leading minus sorts that field in descending order
+func parseSort(v string, allowed []string) ([]db.SortField, error) {
+	var sort []db.SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(v, ",") {
+		field := strings.TrimSpace(part)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		if !slices.Contains(allowed, field) {
+			return nil, fmt.Errorf("unknown sort field %q, allowed fields are: %s", field, strings.Join(allowed, ", "))
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("sort field %q is listed more than once", field)
+		}
+		seen[field] = true
+		sort = append(sort, db.SortField{Field: field, Desc: desc})
+	}
+	return sort, nil
+}
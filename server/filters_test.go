@@ -0,0 +1,65 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/money"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProductFilter(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/products?min_price=10&max_price=20.5&q=lamp&created_after=2024-01-01&sort=price,-created_at", nil)
+	filter, err := parseProductFilter(r, productListParams)
+	require.NoError(t, err)
+
+	require.NotNil(t, filter.MinPrice)
+	require.NotNil(t, filter.MaxPrice)
+	require.NotNil(t, filter.CreatedAfter)
+	assert.Equal(t, money.MustParse("10", "USD"), *filter.MinPrice)
+	assert.Equal(t, money.MustParse("20.50", "USD"), *filter.MaxPrice)
+	assert.Equal(t, "lamp", filter.Query)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *filter.CreatedAfter)
+	assert.Equal(t, []db.SortField{{Field: "price"}, {Field: "created_at", Desc: true}}, filter.Sort)
+	assert.Nil(t, filter.Attributes)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products?attr.brand=Acme&attr.brand=Globex&attr.material=cotton", nil)
+	filter, err = parseProductFilter(r, productListParams)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"brand": {"Acme", "Globex"}, "material": {"cotton"}}, filter.Attributes)
+}
+
+func TestParseProductFilterInvalid(t *testing.T) {
+	tests := []struct {
+		query    string
+		contains string
+	}{
+		{query: "color=red", contains: "allowed parameters are"},
+		{query: "sort=stock", contains: "allowed fields are"},
+		{query: "sort=price,-price", contains: "more than once"},
+		{query: "min_price=-1", contains: "min_price"},
+		{query: "max_price=abc", contains: "max_price"},
+		{query: "min_price=20&max_price=10", contains: "greater than"},
+		{query: "created_after=yesterday", contains: "created_after"},
+		{query: "include_archived=true", contains: "allowed parameters are"},
+		{query: "attr.=red", contains: "allowed parameters are"},
+		{query: "attr.Color=red", contains: "attribute names"},
+		{query: "attr.color=", contains: "must not be empty"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/products?"+tt.query, nil)
+		_, err := parseProductFilter(r, productListParams)
+		require.Error(t, err, tt.query)
+		assert.Contains(t, err.Error(), tt.contains, tt.query)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/products?include_archived=maybe", nil)
+	_, err := parseProductFilter(r, adminProductListParams)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "include_archived")
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/storage"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// imagesPath is the route stored images are served from
+	imagesPath = "/images/"
+	// maxImagesPerUpload caps the number of files in one upload request
+	maxImagesPerUpload = 10
+	// imageCacheControl lets clients cache images forever, stored blobs are
+	// never overwritten so a key always points at the same content
+	imageCacheControl = "public, max-age=31536000, immutable"
+)
+
+// imageExtensions maps the accepted image content types to the file
+// extension of their storage key
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// uploadedImage is an upload that passed validation and is ready to store
+type uploadedImage struct {
+	header      *multipart.FileHeader
+	contentType string
+}
+
+func (s *Server) uploadProductImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	maxSize := s.cfg.Storage.MaxImageSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxImagesPerUpload*(maxSize+1<<10))
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "upload is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	headers := r.MultipartForm.File["images"]
+	if len(headers) == 0 {
+		http.Error(w, "at least one file is required in the images field", http.StatusBadRequest)
+		return
+	}
+	if len(headers) > maxImagesPerUpload {
+		http.Error(w, fmt.Sprintf("at most %d images can be uploaded at once", maxImagesPerUpload), http.StatusBadRequest)
+		return
+	}
+
+	// Validate every file before storing any, so a bad file rejects the
+	// whole upload
+	uploads := make([]uploadedImage, len(headers))
+	for i, header := range headers {
+		if header.Size > maxSize {
+			http.Error(w, fmt.Sprintf("%s is larger than %d bytes", header.Filename, maxSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		contentType, err := detectImageType(header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		uploads[i] = uploadedImage{header: header, contentType: contentType}
+	}
+
+	if _, err := s.db.GetProduct(r.Context(), productID); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	images := make([]db.ProductImage, 0, len(uploads))
+	for _, upload := range uploads {
+		image, err := s.storeProductImage(r, productID, upload)
+		if err != nil {
+			writeDBError(w, err)
+			return
+		}
+		images = append(images, image)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(images)
+}
+
+func (s *Server) deleteProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	imageID, err := strconv.ParseInt(r.PathValue("imageId"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Renditions go with the image row, their keys are needed to remove the files
+	renditions, err := s.db.GetImageRenditions(r.Context(), []int64{imageID})
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	image, err := s.db.DeleteProductImage(r.Context(), productID, imageID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	keys := []string{image.Key}
+	for _, rendition := range renditions {
+		keys = append(keys, rendition.Key)
+	}
+	s.deleteBlobs(r.Context(), keys)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveImage streams a stored image. Keys are never reused, so responses
+// can be cached by clients and proxies for as long as they like.
+func (s *Server) serveImage(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	contentType, ok := imageContentType(key)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	blob, err := s.store.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", imageCacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, blob)
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// storeProductImage writes an upload to the blob store and records it. The
+// blob is removed again if the image cannot be recorded.
+func (s *Server) storeProductImage(r *http.Request, productID int64, upload uploadedImage) (db.ProductImage, error) {
+	key, err := newImageKey(upload.contentType)
+	if err != nil {
+		return db.ProductImage{}, err
+	}
+
+	file, err := upload.header.Open()
+	if err != nil {
+		return db.ProductImage{}, fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer file.Close()
+
+	if err := s.store.Put(r.Context(), key, file); err != nil {
+		return db.ProductImage{}, err
+	}
+	image, err := s.db.CreateProductImage(r.Context(), productID, key, upload.contentType, upload.header.Size)
+	if err != nil {
+		s.deleteBlobs(r.Context(), []string{key})
+		return db.ProductImage{}, err
+	}
+	image.URL = imageURL(image.Key)
+	image.Srcset = []db.ImageRendition{}
+	return image, nil
+}
+
+// deleteBlobs removes the stored files of deleted images. The rows are
+// already gone, so failures are only logged.
+func (s *Server) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to delete image %q: %v\n", key, err)
+		}
+	}
+}
+
+// attachImages loads the uploaded images of a product along with their
+// renditions and fills in their URLs
+func (s *Server) attachImages(r *http.Request, product *db.Product) error {
+	images, err := s.db.GetProductImages(r.Context(), product.ID)
+	if err != nil {
+		return err
+	}
+	ids := make([]int64, len(images))
+	for i := range images {
+		ids[i] = images[i].ID
+	}
+	renditions, err := s.db.GetImageRenditions(r.Context(), ids)
+	if err != nil {
+		return err
+	}
+	srcsets := make(map[int64][]db.ImageRendition)
+	for _, rendition := range renditions {
+		rendition.URL = imageURL(rendition.Key)
+		srcsets[rendition.ImageID] = append(srcsets[rendition.ImageID], rendition)
+	}
+
+	for i := range images {
+		images[i].URL = imageURL(images[i].Key)
+		images[i].Srcset = srcsets[images[i].ID]
+		if images[i].Srcset == nil {
+			images[i].Srcset = []db.ImageRendition{}
+		}
+	}
+	product.Images = images
+	return nil
+}
+
+// detectImageType sniffs the content type of an upload rather than
+// trusting the one sent by the client
+func detectImageType(header *multipart.FileHeader) (string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("%s is empty", header.Filename)
+	}
+	contentType := http.DetectContentType(head[:n])
+	if _, ok := imageExtensions[contentType]; !ok {
+		return "", fmt.Errorf("%s has unsupported type %s, expected JPEG, PNG, GIF or WebP", header.Filename, contentType)
+	}
+	return contentType, nil
+}
+
+// newImageKey returns a random storage key with the extension of contentType
+func newImageKey(contentType string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate image key: %w", err)
+	}
+	return hex.EncodeToString(b) + imageExtensions[contentType], nil
+}
+
+// imageContentType returns the content type of a stored image from its key
+func imageContentType(key string) (string, bool) {
+	ext := path.Ext(key)
+	for contentType, e := range imageExtensions {
+		if strings.EqualFold(e, ext) {
+			return contentType, true
+		}
+	}
+	return "", false
+}
+
+func imageURL(key string) string {
+	return imagesPath + key
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadProductImages(t *testing.T) {
+	t.Setenv("STORAGE_DIR", t.TempDir())
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	pngData := testPNG(t)
+	tests := []struct {
+		id       string
+		files    map[string][]byte
+		expected int
+	}{
+		{id: "1", files: map[string][]byte{"front.png": pngData, "back.png": pngData}, expected: http.StatusCreated},
+		{id: "1", files: map[string][]byte{"notes.txt": []byte("not an image")}, expected: http.StatusUnsupportedMediaType},
+		{id: "1", files: map[string][]byte{}, expected: http.StatusBadRequest},
+		{id: "42", files: map[string][]byte{"front.png": pngData}, expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		body, contentType := multipartImages(t, tt.files)
+		r := httptest.NewRequest(http.MethodPost, "/api/products/"+tt.id+"/images", body)
+		r.Header.Set("Content-Type", contentType)
+		r.SetPathValue("id", tt.id)
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+		srv.uploadProductImages(w, r.WithContext(authCtx))
+		require.Equal(t, tt.expected, w.Code, w.Body.String())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	require.Len(t, product.Images, 2)
+	assert.Equal(t, "image/png", product.Images[0].ContentType)
+	assert.Equal(t, int64(len(pngData)), product.Images[0].Size)
+	assert.NotEqual(t, product.Images[0].URL, product.Images[1].URL)
+
+	r = httptest.NewRequest(http.MethodGet, product.Images[0].URL, nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, imageCacheControl, w.Header().Get("Cache-Control"))
+	assert.Equal(t, pngData, w.Body.Bytes())
+
+	r = httptest.NewRequest(http.MethodDelete, "/api/products/1/images/1", nil)
+	r.SetPathValue("id", "1")
+	r.SetPathValue("imageId", "1")
+	w = httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.deleteProductImage(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, product.Images[0].URL, nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUploadProductImageTooLarge(t *testing.T) {
+	t.Setenv("STORAGE_DIR", t.TempDir())
+	t.Setenv("IMAGE_MAX_SIZE", "64")
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	body, contentType := multipartImages(t, map[string][]byte{"large.png": append(testPNG(t), make([]byte, 128)...)})
+	r := httptest.NewRequest(http.MethodPost, "/api/products/1/images", body)
+	r.Header.Set("Content-Type", contentType)
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.uploadProductImages(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	images, err := database.GetProductImages(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, images)
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+// multipartImages builds an upload body with each file in the images field
+func multipartImages(t *testing.T, files map[string][]byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, data := range files {
+		part, err := mw.CreateFormFile("images", name)
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+	return &body, mw.FormDataContentType()
+}
@@ -0,0 +1,374 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxImportSize caps the size of an uploaded import file
+	maxImportSize = 100 << 20
+	// maxImportErrors caps the row errors listed in an import report, the
+	// rest are only counted
+	maxImportErrors = 100
+	// maxImportLine caps the length of a single NDJSON line
+	maxImportLine = 1 << 20
+	// maxExternalIDLength matches the size of the external_id column
+	maxExternalIDLength = 255
+)
+
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// exportColumns are the CSV columns of an export. The import matches rows
+// to products by id and ignores created_at, so an export can be edited and
+// imported back.
+var exportColumns = []string{"id", "external_id", "name", "description", "price", "currency", "image", "created_at"}
+
+// importRowError reports why a row of an import was skipped
+type importRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type importReport struct {
+	DryRun  bool             `json:"dry_run"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Errors  []importRowError `json:"errors"`
+}
+
+// invalidRowError marks a row that could not be decoded, the import skips
+// it and goes on with the next one
+type invalidRowError struct {
+	err error
+}
+
+func (e invalidRowError) Error() string { return e.err.Error() }
+
+// importRowReader yields the rows of an import one at a time along with the
+// line they start on. It returns io.EOF after the last row.
+type importRowReader interface {
+	Next() (int, db.ProductImport, error)
+}
+
+func (s *Server) importProducts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	if err := checkQueryParams(r, []string{"dry_run"}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "dry_run must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var rows importRowReader
+	var err error
+	switch mediaType {
+	case "text/csv":
+		rows, err = newCSVRowReader(r.Body)
+	case "application/x-ndjson", "application/ndjson":
+		rows = newNDJSONRowReader(r.Body)
+	default:
+		http.Error(w, "Content-Type must be text/csv or application/x-ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	importer, err := s.db.BeginProductImport(r.Context(), userID, dryRun)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	defer importer.Close(r.Context())
+
+	report := importReport{DryRun: dryRun, Errors: []importRowError{}}
+	for {
+		line, row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var invalid invalidRowError
+		if errors.As(err, &invalid) {
+			report.fail(line, err)
+			continue
+		}
+		if err != nil {
+			writeImportError(w, err)
+			return
+		}
+		if err := validateProductImport(row); err != nil {
+			report.fail(line, err)
+			continue
+		}
+
+		// A row the database turns down is rolled back on its own, should
+		// the import itself be broken the commit below fails
+		created, err := importer.Upsert(r.Context(), row)
+		if err != nil {
+			report.fail(line, err)
+			continue
+		}
+		if created {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+
+	if err := importer.Commit(r.Context()); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// exportProducts streams the whole catalog as CSV or NDJSON. Rows are
+// written as they are read, so the status is sent before the first row and
+// a failure midway can only cut the response short.
+func (s *Server) exportProducts(w http.ResponseWriter, r *http.Request) {
+	if err := checkQueryParams(r, []string{"format"}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatCSV
+	}
+
+	var write func(db.ProductExport) error
+	var flush func() error
+	switch format {
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="products.csv"`)
+		w.WriteHeader(http.StatusOK)
+		if err := cw.Write(exportColumns); err != nil {
+			return
+		}
+		write = func(p db.ProductExport) error {
+			externalID := ""
+			if p.ExternalID != nil {
+				externalID = *p.ExternalID
+			}
+			return cw.Write([]string{
+				strconv.FormatInt(p.ID, 10), externalID, p.Name, p.Description,
+				p.Price.Decimal(), p.Price.Currency(), p.Image, p.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="products.ndjson"`)
+		w.WriteHeader(http.StatusOK)
+		write = func(p db.ProductExport) error { return enc.Encode(p) }
+		flush = func() error { return nil }
+	default:
+		http.Error(w, "format must be csv or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.ExportProducts(r.Context(), write); err != nil {
+		log.Printf("Product export failed: %v\n", err)
+		return
+	}
+	if err := flush(); err != nil {
+		log.Printf("Product export failed: %v\n", err)
+	}
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func (rep *importReport) fail(line int, err error) {
+	rep.Failed++
+	if len(rep.Errors) < maxImportErrors {
+		rep.Errors = append(rep.Errors, importRowError{Line: line, Error: err.Error()})
+	}
+}
+
+func writeImportError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("import is larger than %d bytes", maxImportSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func validateProductImport(row db.ProductImport) error {
+	if row.ID == 0 && row.ExternalID == "" && row.SKU == "" {
+		return fmt.Errorf("id, external_id or sku is required")
+	}
+	if row.ID < 0 {
+		return fmt.Errorf("id must be a positive id")
+	}
+	if len(row.ExternalID) > maxExternalIDLength {
+		return fmt.Errorf("external_id must be at most %d characters", maxExternalIDLength)
+	}
+	if row.SKU != "" && !skuPattern.MatchString(row.SKU) {
+		return fmt.Errorf("sku must be 1-64 letters, digits, '.', '_' or '-'")
+	}
+	return validateClientProduct(row.ClientProduct)
+}
+
+// csvRowReader reads import rows from CSV with a header line. Columns are
+// matched by name, unknown columns are ignored.
+type csvRowReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVRowReader(body io.Reader) (*csvRowReader, error) {
+	r := csv.NewReader(body)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("CSV header is missing")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheet tools like to start files with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must include a %s column", required)
+		}
+	}
+	return &csvRowReader{r: r, columns: columns}, nil
+}
+
+func (c *csvRowReader) Next() (int, db.ProductImport, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, db.ProductImport{}, invalidRowError{err}
+		}
+		return 0, db.ProductImport{}, err
+	}
+	line, _ := c.r.FieldPos(0)
+
+	get := func(column string) string {
+		i, ok := c.columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	currency := get("currency")
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	price, err := money.Parse(get("price"), currency)
+	if err != nil {
+		return line, db.ProductImport{}, invalidRowError{fmt.Errorf("price: %w", err)}
+	}
+	var id int64
+	if v := get("id"); v != "" {
+		if id, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return line, db.ProductImport{}, invalidRowError{fmt.Errorf("id must be a number")}
+		}
+	}
+
+	return line, db.ProductImport{
+		ID:         id,
+		ExternalID: get("external_id"),
+		SKU:        get("sku"),
+		ClientProduct: db.ClientProduct{
+			Name:        get("name"),
+			Price:       price,
+			Image:       get("image"),
+			Description: get("description"),
+		},
+	}, nil
+}
+
+// ndjsonRowReader reads import rows from newline delimited JSON, one
+// product object per line. Blank lines are skipped, rows without a price are
+// reported as failed rows like rows that cannot be decoded.
+type ndjsonRowReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONRowReader(body io.Reader) *ndjsonRowReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLine)
+	return &ndjsonRowReader{scanner: scanner}
+}
+
+func (n *ndjsonRowReader) Next() (int, db.ProductImport, error) {
+	for n.scanner.Scan() {
+		n.line++
+		data := bytes.TrimSpace(n.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var row db.ProductImport
+		if err := json.Unmarshal(data, &row); err != nil {
+			return n.line, db.ProductImport{}, invalidRowError{err}
+		}
+		// A missing price decodes to zero, which would import as free
+		var price struct {
+			Price json.RawMessage `json:"price"`
+		}
+		json.Unmarshal(data, &price)
+		if len(price.Price) == 0 {
+			return n.line, db.ProductImport{}, invalidRowError{fmt.Errorf("price is required")}
+		}
+		return n.line, row, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return n.line + 1, db.ProductImport{}, fmt.Errorf("line %d is longer than %d bytes", n.line+1, maxImportLine)
+		}
+		return n.line, db.ProductImport{}, err
+	}
+	return n.line, db.ProductImport{}, io.EOF
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportProducts(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+	_, err = database.CreateVariant(ctx, 1, db.ClientVariant{SKU: "TS-M"})
+	require.NoError(t, err)
+
+	body := "external_id,sku,name,price,image,description\n" +
+		"erp-1,TS-M,Classic T-Shirt,17.99,https://via.placeholder.com/150,Cotton\n" +
+		"erp-2,,Hoodie,49.99,https://via.placeholder.com/150,Fleece\n" +
+		",,Nameless,1.00,https://via.placeholder.com/150,\n" +
+		"erp-3,,Cap,cheap,https://via.placeholder.com/150,\n"
+
+	for _, dryRun := range []bool{true, false} {
+		url := "/api/admin/products/import"
+		if dryRun {
+			url += "?dry_run=true"
+		}
+		r := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		r.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+		srv.importProducts(w, r.WithContext(authCtx))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var report importReport
+		err = json.NewDecoder(w.Body).Decode(&report)
+		require.NoError(t, err)
+		assert.Equal(t, dryRun, report.DryRun)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 1, report.Updated)
+		assert.Equal(t, 2, report.Failed)
+		require.Len(t, report.Errors, 2)
+		assert.Equal(t, 4, report.Errors[0].Line)
+		assert.Equal(t, 5, report.Errors[1].Line)
+
+		product, err := database.GetProduct(ctx, 1)
+		require.NoError(t, err)
+		if dryRun {
+			assert.Equal(t, "T-Shirt", product.Name)
+		} else {
+			assert.Equal(t, "Classic T-Shirt", product.Name)
+		}
+	}
+
+	ndjson := `{"external_id": "erp-2", "name": "Zip Hoodie", "price": {"amount": "54.99", "currency": "USD"}, "image": "https://via.placeholder.com/150"}
+
+{"external_id": "erp-4", "name": "Socks", "price": {"amount": "4.99", "currency": "EUR"}, "image": "https://via.placeholder.com/150"}
+{"external_id": "erp-5", "name":`
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/products/import", strings.NewReader(ndjson))
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.importProducts(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report importReport
+	err = json.NewDecoder(w.Body).Decode(&report)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, 3, report.Errors[0].Line)
+	assert.Equal(t, 4, report.Errors[1].Line)
+
+	// Rows the database turns down are reported without aborting the import
+	body = "external_id,name,price,image\n" +
+		"erp-6," + strings.Repeat("x", 256) + ",5.00,https://via.placeholder.com/150\n" +
+		"erp-7,Scarf,5.00,https://via.placeholder.com/150\n"
+	r = httptest.NewRequest(http.MethodPost, "/api/admin/products/import", strings.NewReader(body))
+	r.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	srv.importProducts(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	report = importReport{}
+	err = json.NewDecoder(w.Body).Decode(&report)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Failed)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, 2, report.Errors[0].Line)
+
+	for _, tt := range []struct {
+		contentType string
+		body        string
+		expected    int
+	}{
+		{contentType: "application/json", body: "{}", expected: http.StatusUnsupportedMediaType},
+		{contentType: "text/csv", body: "external_id,name\nerp-1,T-Shirt\n", expected: http.StatusBadRequest},
+		{contentType: "text/csv", body: "", expected: http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/api/admin/products/import", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+		srv.importProducts(w, r.WithContext(authCtx))
+		require.Equal(t, tt.expected, w.Code, tt.body)
+	}
+}
+
+func TestExportProducts(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Soft, \"comfy\" cotton"},
+		{ID: 2, Name: "Hoodie", Price: money.MustParse("49.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/products/export", nil)
+	w := httptest.NewRecorder()
+	srv.exportProducts(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+	exportedCSV := w.Body.String()
+	records, err := csv.NewReader(strings.NewReader(exportedCSV)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, exportColumns, records[0])
+	assert.Equal(t, []string{"1", "", "T-Shirt", "Soft, \"comfy\" cotton", "19.99", "USD", "https://via.placeholder.com/150"}, records[1][:7])
+
+	// Products without an external id or SKU are imported back by id
+	r = httptest.NewRequest(http.MethodPost, "/api/admin/products/import", strings.NewReader(exportedCSV))
+	r.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.importProducts(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report importReport
+	err = json.NewDecoder(w.Body).Decode(&report)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Created)
+	assert.Equal(t, 2, report.Updated)
+	assert.Equal(t, 0, report.Failed)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/admin/products/export?format=ndjson", nil)
+	w = httptest.NewRecorder()
+	srv.exportProducts(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	dec := json.NewDecoder(w.Body)
+	var exported []db.ProductExport
+	for {
+		var p db.ProductExport
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		exported = append(exported, p)
+	}
+	require.Len(t, exported, 2)
+	assert.Equal(t, "Hoodie", exported[1].Name)
+	assert.Equal(t, money.MustParse("49.99", "USD"), exported[1].Price)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/admin/products/export?format=xml", nil)
+	w = httptest.NewRecorder()
+	srv.exportProducts(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCSVRowReader(t *testing.T) {
+	body := "\ufeffName,Price,Currency,SKU,Extra\n" +
+		"T-Shirt,19.99,,TS-M,ignored\n" +
+		"Cap,\"9.99\n" +
+		"Hoodie,1.234,,HD-1,\n"
+	rows, err := newCSVRowReader(bytes.NewBufferString(body))
+	require.NoError(t, err)
+
+	line, row, err := rows.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, "T-Shirt", row.Name)
+	assert.Equal(t, "TS-M", row.SKU)
+	assert.Equal(t, money.MustParse("19.99", "USD"), row.Price)
+
+	// The unterminated quote swallows the rest of the file
+	_, _, err = rows.Next()
+	var invalid invalidRowError
+	require.ErrorAs(t, err, &invalid)
+
+	_, _, err = rows.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestNDJSONRowReader(t *testing.T) {
+	body := `{"external_id": "erp-1", "name": "T-Shirt", "price": {"amount": "19.99"}}` + "\n\n" +
+		`{"name": 42}` + "\n" +
+		`{"external_id": "erp-2", "name": "Cap"}` + "\n" +
+		`{"external_id": "erp-3", "name": "Cap", "price": null}` + "\n"
+	rows := newNDJSONRowReader(bytes.NewBufferString(body))
+
+	line, row, err := rows.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 1, line)
+	assert.Equal(t, "erp-1", row.ExternalID)
+	assert.Equal(t, money.MustParse("19.99", "USD"), row.Price)
+
+	line, _, err = rows.Next()
+	var invalid invalidRowError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, 3, line)
+
+	// Rows without a price are not imported as free
+	for _, expected := range []int{4, 5} {
+		line, _, err = rows.Next()
+		require.ErrorAs(t, err, &invalid)
+		assert.Equal(t, expected, line)
+	}
+
+	_, _, err = rows.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// maxReservationQuantity caps how many units a single reservation can hold
+const maxReservationQuantity = 1000
+
+func (s *Server) setStock(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var clientStock db.ClientStock
+	if err := decodeJSON(r, &clientStock); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientStock(clientStock); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	stock, err := s.db.SetStock(r.Context(), productID, clientStock.VariantID, clientStock.Quantity)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stock)
+}
+
+func (s *Server) reserveStock(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var clientReservation db.ClientReservation
+	if err := decodeJSON(r, &clientReservation); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientReservation(clientReservation); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	reservation, err := s.db.ReserveStock(r.Context(), userID, clientReservation.ProductID,
+		clientReservation.VariantID, clientReservation.Quantity, s.cfg.Inventory.ReservationTTL)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reservation)
+}
+
+func (s *Server) commitReservation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.CommitReservation(r.Context(), userID, id); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) releaseReservation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.ReleaseReservation(r.Context(), userID, id); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func validateClientStock(s db.ClientStock) error {
+	var v validator
+	v.check(s.Quantity >= 0, "quantity", "must not be negative")
+	return v.err()
+}
+
+func validateClientReservation(r db.ClientReservation) error {
+	var v validator
+	v.check(r.ProductID > 0, "product_id", "is required")
+	v.check(r.Quantity >= 1 && r.Quantity <= maxReservationQuantity, "quantity", fmt.Sprintf("must be between 1 and %d", maxReservationQuantity))
+	return v.err()
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetStock(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	tests := []struct {
+		id       string
+		body     string
+		expected int
+	}{
+		{id: "1", body: `{"quantity": 5}`, expected: http.StatusOK},
+		{id: "1", body: `{"quantity": -1}`, expected: http.StatusUnprocessableEntity},
+		{id: "1", body: `{"variant_id": 42, "quantity": 5}`, expected: http.StatusNotFound},
+		{id: "42", body: `{"quantity": 5}`, expected: http.StatusNotFound},
+		{id: "abc", body: `{"quantity": 5}`, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/api/products/"+tt.id+"/stock", bytes.NewBufferString(tt.body))
+		r.SetPathValue("id", tt.id)
+		w := httptest.NewRecorder()
+		srv.setStock(w, r)
+		require.Equal(t, tt.expected, w.Code, tt.body)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), product.QuantityAvailable)
+	assert.True(t, product.InStock)
+}
+
+func TestReservations(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	_, err = database.SetStock(ctx, 1, nil, 3)
+	require.NoError(t, err)
+
+	userCtx := context.WithValue(ctx, userIDKey, "user1")
+
+	tests := []struct {
+		body     string
+		expected int
+	}{
+		{body: `{"product_id": 1, "quantity": 0}`, expected: http.StatusUnprocessableEntity},
+		{body: `{"product_id": 1, "quantity": 4}`, expected: http.StatusConflict},
+		{body: `{"product_id": 42, "quantity": 1}`, expected: http.StatusNotFound},
+		{body: `{"product_id": 1, "quantity": 2}`, expected: http.StatusCreated},
+	}
+
+	var reservation db.Reservation
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/reservations", bytes.NewBufferString(tt.body)).WithContext(userCtx)
+		w := httptest.NewRecorder()
+		srv.reserveStock(w, r)
+		require.Equal(t, tt.expected, w.Code, tt.body)
+		if w.Code == http.StatusCreated {
+			err = json.NewDecoder(w.Body).Decode(&reservation)
+			require.NoError(t, err)
+		}
+	}
+	id := strconv.FormatInt(reservation.ID, 10)
+
+	// Another user cannot commit the reservation
+	r := httptest.NewRequest(http.MethodPost, "/api/reservations/"+id+"/commit", nil).
+		WithContext(context.WithValue(ctx, userIDKey, "user2"))
+	r.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+	srv.commitReservation(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	r = httptest.NewRequest(http.MethodPost, "/api/reservations/"+id+"/commit", nil).WithContext(userCtx)
+	r.SetPathValue("id", id)
+	w = httptest.NewRecorder()
+	srv.commitReservation(w, r)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	product, err := database.GetProduct(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), product.QuantityAvailable)
+
+	r = httptest.NewRequest(http.MethodDelete, "/api/reservations/"+id, nil).WithContext(userCtx)
+	r.SetPathValue("id", id)
+	w = httptest.NewRecorder()
+	srv.releaseReservation(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
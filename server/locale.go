@@ -0,0 +1,199 @@
+package server
+
+import (
+	"catalogapi/config"
+	"catalogapi/db"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// maxTranslationNameLength matches the size of the translated name column
+const maxTranslationNameLength = 255
+
+// locales negotiates the locale a request is served in among the
+// configured ones
+type locales struct {
+	// supported holds the canonical form of the supported locales, the
+	// default one first
+	supported []string
+	matcher   language.Matcher
+}
+
+func newLocales(cfg config.LocaleConfig) (*locales, error) {
+	tags := make([]language.Tag, len(cfg.Supported))
+	supported := make([]string, len(cfg.Supported))
+	for i, locale := range cfg.Supported {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid locale %q: %w", locale, err)
+		}
+		tags[i] = tag
+		supported[i] = tag.String()
+	}
+	return &locales{supported: supported, matcher: language.NewMatcher(tags)}, nil
+}
+
+func (l *locales) defaultLocale() string {
+	return l.supported[0]
+}
+
+// negotiate picks the locale of a request from its locale query parameter,
+// or else its Accept-Language header. A locale that is not supported falls
+// back to the closest supported one, and to the default locale when none
+// is close enough.
+func (l *locales) negotiate(r *http.Request) (string, error) {
+	var preferred []language.Tag
+	if v := r.URL.Query().Get("locale"); v != "" {
+		tag, err := language.Parse(v)
+		if err != nil {
+			return "", fmt.Errorf("locale must be a BCP 47 language tag such as en or fr-CA")
+		}
+		preferred = []language.Tag{tag}
+	} else if v := r.Header.Get("Accept-Language"); v != "" {
+		// A malformed header falls back to the default locale rather than
+		// failing the request
+		preferred, _, _ = language.ParseAcceptLanguage(v)
+	}
+
+	_, index, confidence := l.matcher.Match(preferred...)
+	if confidence == language.No {
+		return l.defaultLocale(), nil
+	}
+	return l.supported[index], nil
+}
+
+// canonical returns the canonical form of a supported locale
+func (l *locales) canonical(locale string) (string, bool) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", false
+	}
+	canonical := tag.String()
+	return canonical, slices.Contains(l.supported, canonical)
+}
+
+// localize serves products in locale and reports the locales they ended up
+// in through Content-Language, products without a translation stay in the
+// default locale
+func (s *Server) localize(w http.ResponseWriter, r *http.Request, locale string, products []*db.Product) error {
+	if err := s.db.Localize(r.Context(), locale, s.locales.defaultLocale(), products); err != nil {
+		return err
+	}
+
+	var served []string
+	for _, p := range products {
+		if !slices.Contains(served, p.Locale) {
+			served = append(served, p.Locale)
+		}
+	}
+	if len(served) == 0 {
+		served = []string{locale}
+	}
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Language", strings.Join(served, ", "))
+	return nil
+}
+
+func (s *Server) getProductTranslations(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	translations, err := s.db.GetProductTranslations(r.Context(), id)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if translations == nil {
+		translations = []db.ProductTranslation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(translations)
+}
+
+func (s *Server) setProductTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locale, err := s.translationLocale(r.PathValue("locale"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var translation db.ClientTranslation
+	if err := decodeJSON(r, &translation); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateTranslation(translation); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	saved, err := s.db.SetProductTranslation(r.Context(), id, locale, translation)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(saved)
+}
+
+func (s *Server) deleteProductTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locale, err := s.translationLocale(r.PathValue("locale"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteProductTranslation(r.Context(), id, locale); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+// translationLocale checks the locale of a translation path. The default
+// locale is stored on the product itself and cannot be translated into.
+func (s *Server) translationLocale(v string) (string, error) {
+	locale, ok := s.locales.canonical(v)
+	if !ok {
+		return "", fmt.Errorf("locale %q is not supported, supported locales are: %s", v, strings.Join(s.locales.supported, ", "))
+	}
+	if locale == s.locales.defaultLocale() {
+		return "", fmt.Errorf("%s is the default locale, update the product instead", locale)
+	}
+	return locale, nil
+}
+
+func validateTranslation(t db.ClientTranslation) error {
+	var v validator
+	v.required("name", t.Name)
+	v.maxLength("name", t.Name, maxTranslationNameLength)
+	return v.err()
+}
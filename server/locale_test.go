@@ -0,0 +1,127 @@
+package server
+
+import (
+	"catalogapi/config"
+	"catalogapi/db"
+	"catalogapi/money"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	l, err := newLocales(config.LocaleConfig{Default: "en", Supported: []string{"en", "fr", "de"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		query          string
+		acceptLanguage string
+		expected       string
+	}{
+		{expected: "en"},
+		{acceptLanguage: "fr-CA,fr;q=0.9,en;q=0.5", expected: "fr"},
+		{acceptLanguage: "ja", expected: "en"},
+		{acceptLanguage: "not a language;;", expected: "en"},
+		{query: "locale=de", acceptLanguage: "fr", expected: "de"},
+		{query: "locale=he", expected: "en"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/products?"+tt.query, nil)
+		if tt.acceptLanguage != "" {
+			r.Header.Set("Accept-Language", tt.acceptLanguage)
+		}
+		locale, err := l.negotiate(r)
+		require.NoError(t, err, tt)
+		assert.Equal(t, tt.expected, locale, tt)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products?locale=!!", nil)
+	_, err = l.negotiate(r)
+	require.Error(t, err)
+}
+
+func TestLocalizedProducts(t *testing.T) {
+	t.Setenv("DEFAULT_LOCALE", "en")
+	t.Setenv("SUPPORTED_LOCALES", "fr")
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150", Description: "A sturdy desk"},
+		{ID: 2, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "A bright lamp"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	for locale, expected := range map[string]int{"fr": http.StatusOK, "en": http.StatusBadRequest, "ja": http.StatusBadRequest} {
+		r := httptest.NewRequest(http.MethodPut, "/api/products/1/translations/"+locale,
+			strings.NewReader(`{"name": "Bureau en chêne", "description": "Un bureau solide"}`))
+		r.SetPathValue("id", "1")
+		r.SetPathValue("locale", locale)
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+		srv.setProductTranslation(w, r.WithContext(authCtx))
+		require.Equal(t, expected, w.Code, locale)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+	r.Header.Set("Accept-Language", "fr-FR,fr;q=0.9")
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "fr", w.Header().Get("Content-Language"))
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	assert.Equal(t, "Bureau en chêne", product.Name)
+	assert.Equal(t, "Un bureau solide", product.Description)
+
+	// The untranslated product falls back to the default locale
+	r = httptest.NewRequest(http.MethodGet, "/api/products?locale=fr", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "fr, en", w.Header().Get("Content-Language"))
+
+	var res listResponse[db.Product]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.Len(t, res.Items, 2)
+	assert.Equal(t, "fr", res.Items[0].Locale)
+	assert.Equal(t, "Desk Lamp", res.Items[1].Name)
+	assert.Equal(t, "en", res.Items[1].Locale)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "en", w.Header().Get("Content-Language"))
+
+	// Category listings are localized the same way
+	err = db.PopulateTestData(ctx, database, "categories", []db.Category{{ID: 1, Name: "Desks", Slug: "desks"}})
+	require.NoError(t, err)
+	require.NoError(t, database.SetProductCategories(ctx, 1, []int64{1}))
+
+	r = httptest.NewRequest(http.MethodGet, "/api/categories/1/products", nil)
+	r.Header.Set("Accept-Language", "fr")
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "fr", w.Header().Get("Content-Language"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Language")
+
+	res = listResponse[db.Product]{}
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.Len(t, res.Items, 1)
+	assert.Equal(t, "Bureau en chêne", res.Items[0].Name)
+}
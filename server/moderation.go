@@ -0,0 +1,172 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// maxModerationBatch caps how many reviews a single batch moderates
+	maxModerationBatch       = 100
+	maxRejectionReasonLength = 500
+)
+
+// reviewModeration is the payload accepted when moderating reviews. IDs is
+// only read by the batch endpoints, Reason only when rejecting.
+type reviewModeration struct {
+	IDs    []int64 `json:"ids"`
+	Reason string  `json:"reason"`
+}
+
+// getReviewQueue lists reviews by moderation status, the pending ones
+// unless another status is asked for
+func (s *Server) getReviewQueue(w http.ResponseWriter, r *http.Request) {
+	if err := checkQueryParams(r, []string{"status", "limit", "cursor"}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = db.ReviewPending
+	}
+	if status != db.ReviewPending && status != db.ReviewApproved && status != db.ReviewRejected {
+		http.Error(w, fmt.Sprintf("status must be %s, %s or %s", db.ReviewPending, db.ReviewApproved, db.ReviewRejected), http.StatusBadRequest)
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reviews, next, err := s.db.GetReviewsByStatus(r.Context(), status, page)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if reviews == nil {
+		reviews = []db.ModeratedReview{}
+	}
+
+	setNextLink(w, r, next)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listResponse[db.ModeratedReview]{Items: reviews, NextCursor: next})
+}
+
+func (s *Server) approveReview(w http.ResponseWriter, r *http.Request) {
+	s.moderateReview(w, r, db.ReviewApproved)
+}
+
+func (s *Server) rejectReview(w http.ResponseWriter, r *http.Request) {
+	s.moderateReview(w, r, db.ReviewRejected)
+}
+
+func (s *Server) approveReviews(w http.ResponseWriter, r *http.Request) {
+	s.moderateReviews(w, r, db.ReviewApproved)
+}
+
+func (s *Server) rejectReviews(w http.ResponseWriter, r *http.Request) {
+	s.moderateReviews(w, r, db.ReviewRejected)
+}
+
+// moderateReview approves or rejects the review in the path
+func (s *Server) moderateReview(w http.ResponseWriter, r *http.Request, status string) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Approving needs no body
+	var moderation reviewModeration
+	if status == db.ReviewRejected || r.ContentLength > 0 {
+		if err := decodeJSON(r, &moderation); err != nil {
+			writeRequestError(w, err)
+			return
+		}
+	}
+	if err := validateReviewModeration(moderation, status, false); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	reviews, err := s.db.ModerateReviews(r.Context(), []int64{id}, status, moderation.Reason, userID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews[0])
+}
+
+// moderateReviews approves or rejects a batch of reviews, all of them or
+// none when one does not exist
+func (s *Server) moderateReviews(w http.ResponseWriter, r *http.Request, status string) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var moderation reviewModeration
+	if err := decodeJSON(r, &moderation); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateReviewModeration(moderation, status, true); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	reviews, err := s.db.ModerateReviews(r.Context(), moderation.IDs, status, moderation.Reason, userID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listResponse[db.ModeratedReview]{Items: reviews})
+}
+
+// newReviewStatus is the status new and edited reviews are put in
+func (s *Server) newReviewStatus() string {
+	if s.cfg.Reviews.AutoApprove {
+		return db.ReviewApproved
+	}
+	return db.ReviewPending
+}
+
+func validateReviewModeration(m reviewModeration, status string, batch bool) error {
+	var v validator
+	if batch {
+		v.check(len(m.IDs) > 0, "ids", "is required")
+		v.check(len(m.IDs) <= maxModerationBatch, "ids", fmt.Sprintf("must hold at most %d ids", maxModerationBatch))
+		for _, id := range m.IDs {
+			if id <= 0 {
+				v.check(false, "ids", "must only hold positive ids")
+				break
+			}
+		}
+	} else {
+		v.check(m.IDs == nil, "ids", "is only accepted by the batch endpoints")
+	}
+	if status == db.ReviewRejected {
+		v.required("reason", m.Reason)
+		v.maxLength("reason", m.Reason, maxRejectionReasonLength)
+	} else {
+		v.check(m.Reason == "", "reason", "is only accepted when rejecting")
+	}
+	return v.err()
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewModeration(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	t.Setenv("REVIEWS_AUTO_APPROVE", "false")
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	var ids []int64
+	for _, user := range []string{"1", "2"} {
+		body := `{"productId": 1, "reviewTitle": "Title", "reviewContent": "Content", "stars": 4}`
+		r := httptest.NewRequest(http.MethodPost, "/api/reviews", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.postReview(w, r.WithContext(context.WithValue(r.Context(), userIDKey, user)))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var review db.SafeReview
+		err = json.NewDecoder(w.Body).Decode(&review)
+		require.NoError(t, err)
+		assert.Equal(t, db.ReviewPending, review.Status)
+		ids = append(ids, review.ID)
+	}
+
+	publicReviews := func() []db.SafeReview {
+		r := httptest.NewRequest(http.MethodGet, "/api/products/1/reviews", nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		var res listResponse[db.SafeReview]
+		err := json.NewDecoder(w.Body).Decode(&res)
+		require.NoError(t, err)
+		return res.Items
+	}
+	assert.Empty(t, publicReviews())
+
+	adminCtx := func(r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), userIDKey, "admin"))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/reviews", nil)
+	w := httptest.NewRecorder()
+	srv.getReviewQueue(w, adminCtx(r))
+	require.Equal(t, http.StatusOK, w.Code)
+	var queue listResponse[db.ModeratedReview]
+	err = json.NewDecoder(w.Body).Decode(&queue)
+	require.NoError(t, err)
+	require.Len(t, queue.Items, 2)
+	assert.Equal(t, "1", queue.Items[0].UserId)
+
+	// Rejecting needs a reason
+	r = httptest.NewRequest(http.MethodPost, "/api/admin/reviews/1/reject", bytes.NewBufferString(`{}`))
+	r.SetPathValue("id", "1")
+	w = httptest.NewRecorder()
+	srv.rejectReview(w, adminCtx(r))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	path := "/api/admin/reviews/" + strconv.FormatInt(ids[0], 10) + "/reject"
+	r = httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{"reason": "Spam"}`))
+	r.SetPathValue("id", strconv.FormatInt(ids[0], 10))
+	w = httptest.NewRecorder()
+	srv.rejectReview(w, adminCtx(r))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var rejected db.ModeratedReview
+	err = json.NewDecoder(w.Body).Decode(&rejected)
+	require.NoError(t, err)
+	assert.Equal(t, db.ReviewRejected, rejected.Status)
+	require.NotNil(t, rejected.RejectionReason)
+	assert.Equal(t, "Spam", *rejected.RejectionReason)
+
+	r = httptest.NewRequest(http.MethodPost, "/api/admin/reviews/approve", bytes.NewBufferString(`{"ids": [`+strconv.FormatInt(ids[1], 10)+`]}`))
+	w = httptest.NewRecorder()
+	srv.approveReviews(w, adminCtx(r))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	reviews := publicReviews()
+	require.Len(t, reviews, 1)
+	assert.Equal(t, ids[1], reviews[0].ID)
+
+	for _, body := range []string{`{"ids": []}`, `{"ids": [-1]}`, `{"ids": [1], "reason": "Fine"}`} {
+		r = httptest.NewRequest(http.MethodPost, "/api/admin/reviews/approve", bytes.NewBufferString(body))
+		w = httptest.NewRecorder()
+		srv.approveReviews(w, adminCtx(r))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, body)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/api/admin/reviews/reject", bytes.NewBufferString(`{"ids": [42], "reason": "Spam"}`))
+	w = httptest.NewRecorder()
+	srv.rejectReviews(w, adminCtx(r))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/admin/reviews?status=unknown", nil)
+	w = httptest.NewRecorder()
+	srv.getReviewQueue(w, adminCtx(r))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"catalogapi/db"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// listResponse is the envelope returned by every paginated endpoint
+type listResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// parsePage reads the limit and cursor query parameters
+func parsePage(r *http.Request) (db.Page, error) {
+	query := r.URL.Query()
+	page := db.Page{Cursor: query.Get("cursor")}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > db.MaxPageLimit {
+			return db.Page{}, fmt.Errorf("limit must be an integer between 1 and %d", db.MaxPageLimit)
+		}
+		page.Limit = n
+	}
+	return page, nil
+}
+
+// setNextLink advertises the next page through an RFC 8288 Link header
+func setNextLink(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	query := r.URL.Query()
+	query.Set("cursor", next)
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) getPriceHistory(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, next, err := s.db.GetPriceHistory(r.Context(), productID, page)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if changes == nil {
+		changes = []db.PriceChange{}
+	}
+
+	setNextLink(w, r, next)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listResponse[db.PriceChange]{Items: changes, NextCursor: next})
+}
+
+func (s *Server) getScheduledPriceChanges(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.GetProduct(r.Context(), productID); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	changes, err := s.db.GetScheduledPriceChanges(r.Context(), productID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if changes == nil {
+		changes = []db.ScheduledPriceChange{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(changes)
+}
+
+func (s *Server) createScheduledPriceChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var clientChange db.ClientScheduledPriceChange
+	if err := decodeJSON(r, &clientChange); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientScheduledPriceChange(clientChange); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	change, err := s.db.CreateScheduledPriceChange(r.Context(), productID, clientChange, userID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(change)
+}
+
+func (s *Server) cancelScheduledPriceChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	changeID, err := strconv.ParseInt(r.PathValue("changeId"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.CancelScheduledPriceChange(r.Context(), productID, changeID, userID); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func validateClientScheduledPriceChange(c db.ClientScheduledPriceChange) error {
+	var v validator
+	v.price("price", c.Price)
+	v.check(!c.StartsAt.IsZero(), "starts_at", "is required")
+	if c.EndsAt != nil && !c.StartsAt.IsZero() {
+		v.check(c.EndsAt.After(c.StartsAt), "ends_at", "must be after starts_at")
+	}
+	return v.err()
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateScheduledPriceChange(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	tests := []struct {
+		id       string
+		body     string
+		expected int
+	}{
+		{id: "1", body: `{"price": {"amount": "14.99", "currency": "USD"}, "starts_at": "2030-11-27T00:00:00Z", "ends_at": "2030-12-01T00:00:00Z"}`, expected: http.StatusCreated},
+		{id: "1", body: `{"price": {"amount": "12.99", "currency": "USD"}, "starts_at": "2030-11-30T00:00:00Z"}`, expected: http.StatusConflict},
+		{id: "1", body: `{"price": {"amount": "24.99", "currency": "USD"}, "starts_at": "2031-01-01T00:00:00Z"}`, expected: http.StatusCreated},
+		{id: "1", body: `{"price": {"amount": "14.99", "currency": "USD"}, "starts_at": "2030-12-01T00:00:00Z", "ends_at": "2030-11-27T00:00:00Z"}`, expected: http.StatusUnprocessableEntity},
+		{id: "1", body: `{"price": {"amount": "14.99", "currency": "USD"}}`, expected: http.StatusUnprocessableEntity},
+		{id: "1", body: `{"price": {"amount": "-1", "currency": "USD"}, "starts_at": "2032-01-01T00:00:00Z"}`, expected: http.StatusUnprocessableEntity},
+		{id: "42", body: `{"price": {"amount": "14.99", "currency": "USD"}, "starts_at": "2032-01-01T00:00:00Z"}`, expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/products/"+tt.id+"/scheduled-prices", bytes.NewBufferString(tt.body))
+		r.SetPathValue("id", tt.id)
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+		srv.createScheduledPriceChange(w, r.WithContext(authCtx))
+		require.Equal(t, tt.expected, w.Code, tt.body)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1/scheduled-prices", nil)
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	srv.getScheduledPriceChanges(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var changes []db.ScheduledPriceChange
+	err = json.NewDecoder(w.Body).Decode(&changes)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "admin", changes[0].CreatedBy)
+	assert.Equal(t, db.PriceChangePending, changes[0].Status)
+
+	r = httptest.NewRequest(http.MethodDelete, "/api/products/1/scheduled-prices/1", nil)
+	r.SetPathValue("id", "1")
+	r.SetPathValue("changeId", "1")
+	w = httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.cancelScheduledPriceChange(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.cancelScheduledPriceChange(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestGetPriceHistory(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPatch, "/api/products/1", bytes.NewBufferString(`{"price": {"amount": "17.99", "currency": "USD"}}`))
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.patchProduct(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products/1/price-history", nil)
+	r.SetPathValue("id", "1")
+	w = httptest.NewRecorder()
+	srv.getPriceHistory(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response listResponse[db.PriceChange]
+	err = json.NewDecoder(w.Body).Decode(&response)
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	assert.Equal(t, money.MustParse("19.99", "USD"), response.Items[0].OldPrice)
+	assert.Equal(t, money.MustParse("17.99", "USD"), response.Items[0].NewPrice)
+	assert.Equal(t, "admin", response.Items[0].ChangedBy)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products/42/price-history", nil)
+	r.SetPathValue("id", "42")
+	w = httptest.NewRecorder()
+	srv.getPriceHistory(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
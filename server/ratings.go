@@ -0,0 +1,29 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// getRatingSummary serves the star histogram of a product's reviews along
+// with their mean, median and Bayesian score
+func (s *Server) getRatingSummary(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	prior := db.RatingPrior{Mean: s.cfg.Ratings.PriorMean, Weight: s.cfg.Ratings.PriorWeight}
+	summary, err := s.db.GetRatingSummary(r.Context(), id, prior)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/money"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRatingSummary(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []db.Review{
+		{ID: 1, UserId: "u1", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 5},
+		{ID: 2, UserId: "u2", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 3},
+	}
+	err = db.PopulateTestData(ctx, database, "reviews", testReviews)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1/rating-summary", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var summary db.RatingSummary
+	err = json.NewDecoder(w.Body).Decode(&summary)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), summary.Count)
+	assert.Equal(t, int64(1), summary.Histogram[5])
+	assert.Equal(t, int64(1), summary.Histogram[3])
+	require.NotNil(t, summary.Mean)
+	assert.Equal(t, 4.0, *summary.Mean)
+	assert.Less(t, summary.BayesianScore, 4.0)
+
+	for path, expected := range map[string]int{
+		"/api/products/42/rating-summary":  http.StatusNotFound,
+		"/api/products/abc/rating-summary": http.StatusBadRequest,
+	} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		assert.Equal(t, expected, w.Code, path)
+	}
+}
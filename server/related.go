@@ -0,0 +1,64 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// productRelatedParams are the query parameters accepted by GET /api/products/{id}/related
+var productRelatedParams = []string{"limit", "currency"}
+
+// maxRelatedLimit caps how many related products a single request can ask for
+const maxRelatedLimit = 50
+
+// getRelatedProducts serves the "customers who reviewed this also reviewed"
+// block of a product
+func (s *Server) getRelatedProducts(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkQueryParams(r, productRelatedParams); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := db.DefaultRelatedLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRelatedLimit {
+			http.Error(w, fmt.Sprintf("limit must be an integer between 1 and %d", maxRelatedLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	currency, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	related, err := s.db.GetRelatedProducts(r.Context(), id, limit)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if related == nil {
+		related = []db.RelatedProduct{}
+	}
+	refs := make([]*db.Product, len(related))
+	for i := range related {
+		refs[i] = &related[i].Product
+	}
+	if err := s.db.ConvertPrices(r.Context(), currency, refs); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listResponse[db.RelatedProduct]{Items: related})
+}
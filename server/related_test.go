@@ -0,0 +1,58 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/money"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRelatedProducts(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150"},
+		{ID: 2, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []db.Review{
+		{ID: 1, UserId: "u1", ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 5},
+		{ID: 2, UserId: "u1", ProductID: 2, ReviewTitle: "Title", ReviewContent: "Content", Stars: 4},
+	}
+	err = db.PopulateTestData(ctx, database, "reviews", testReviews)
+	require.NoError(t, err)
+	_, err = database.RefreshProductRelations(ctx)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1/related", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res listResponse[db.RelatedProduct]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.Len(t, res.Items, 1)
+	validateProduct(t, res.Items[0].Product, testProducts[1])
+	assert.Equal(t, db.RelatedByReviews, res.Items[0].Source)
+
+	for path, expected := range map[string]int{
+		"/api/products/42/related":        http.StatusNotFound,
+		"/api/products/1/related?limit=0": http.StatusBadRequest,
+		"/api/products/1/related?sort=id": http.StatusBadRequest,
+	} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		require.Equal(t, expected, w.Code, path)
+	}
+}
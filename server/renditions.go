@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/imaging"
+	"catalogapi/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// renditionBatchSize is how many images one run of the rendition job picks up
+const renditionBatchSize = 20
+
+// GenerateImageRenditions resizes the images that have no renditions yet
+// and returns how many were processed. Rendition keys are derived from the
+// image key, so two instances processing the same image write identical
+// files and the second one's rows are ignored.
+func (s *Server) GenerateImageRenditions(ctx context.Context) (int, error) {
+	images, err := s.db.GetUnprocessedImages(ctx, renditionBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, image := range images {
+		if err := s.generateRenditions(ctx, image); err != nil {
+			return i, err
+		}
+	}
+	return len(images), nil
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func (s *Server) generateRenditions(ctx context.Context, image db.ProductImage) error {
+	blob, err := s.store.Get(ctx, image.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Skipping renditions of image %d: %v\n", image.ID, err)
+		return s.saveRenditions(ctx, image.ID, nil)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(blob)
+	blob.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read image %q: %w", image.Key, err)
+	}
+
+	src, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrUnsupportedImage) {
+		// Retrying will not help, mark the image processed without renditions
+		log.Printf("Skipping renditions of image %d: %v\n", image.ID, err)
+		return s.saveRenditions(ctx, image.ID, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	var renditions []db.ImageRendition
+	for _, width := range imaging.Sizes(src.Bounds().Dx()) {
+		resized := imaging.Resize(src, width)
+		for _, format := range imaging.Formats {
+			var buf bytes.Buffer
+			if err := imaging.Encode(&buf, resized, format); err != nil {
+				return fmt.Errorf("failed to encode rendition of image %d: %w", image.ID, err)
+			}
+			rendition := db.ImageRendition{
+				Width:       width,
+				Format:      string(format),
+				Key:         renditionKey(image.Key, width, format),
+				ContentType: format.ContentType(),
+				Size:        int64(buf.Len()),
+			}
+			if err := s.store.Put(ctx, rendition.Key, &buf); err != nil {
+				return err
+			}
+			renditions = append(renditions, rendition)
+		}
+	}
+	return s.saveRenditions(ctx, image.ID, renditions)
+}
+
+// saveRenditions records renditions, removing their files again if the
+// image was deleted while they were being generated
+func (s *Server) saveRenditions(ctx context.Context, imageID int64, renditions []db.ImageRendition) error {
+	err := s.db.SaveImageRenditions(ctx, imageID, renditions)
+	if errors.Is(err, db.ErrNotFound) {
+		keys := make([]string, len(renditions))
+		for i, rendition := range renditions {
+			keys[i] = rendition.Key
+		}
+		s.deleteBlobs(ctx, keys)
+		return nil
+	}
+	return err
+}
+
+// renditionKey derives the storage key of a rendition from its image key,
+// for example 3f2a.png becomes 3f2a-400w.webp
+func renditionKey(imageKey string, width int, format imaging.Format) string {
+	base := strings.TrimSuffix(imageKey, path.Ext(imageKey))
+	return base + "-" + strconv.Itoa(width) + "w" + format.Extension()
+}
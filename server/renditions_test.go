@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/imaging"
+	"catalogapi/money"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateImageRenditions(t *testing.T) {
+	t.Setenv("STORAGE_DIR", t.TempDir())
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	var photo bytes.Buffer
+	err = png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 800, 600)))
+	require.NoError(t, err)
+
+	body, contentType := multipartImages(t, map[string][]byte{"front.png": photo.Bytes()})
+	r := httptest.NewRequest(http.MethodPost, "/api/products/1/images", body)
+	r.Header.Set("Content-Type", contentType)
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.uploadProductImages(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	processed, err := srv.GenerateImageRenditions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	// Processed images are not picked up again
+	processed, err = srv.GenerateImageRenditions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, processed)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	require.Len(t, product.Images, 1)
+	srcset := product.Images[0].Srcset
+	require.Len(t, srcset, 6)
+	assert.Equal(t, 150, srcset[0].Width)
+	assert.Equal(t, "image/webp", srcset[0].ContentType)
+	assert.Equal(t, 400, srcset[1].Width)
+	// The original width is kept as the largest rendition
+	assert.Equal(t, 800, srcset[2].Width)
+	assert.Equal(t, "image/jpeg", srcset[3].ContentType)
+
+	r = httptest.NewRequest(http.MethodGet, srcset[1].URL, nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+
+	resized, _, err := image.Decode(w.Body)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 400, 300), resized.Bounds())
+}
+
+func TestGenerateSmallImageRenditions(t *testing.T) {
+	t.Setenv("STORAGE_DIR", t.TempDir())
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	// Narrower than the smallest rendition width
+	var icon bytes.Buffer
+	err = png.Encode(&icon, image.NewRGBA(image.Rect(0, 0, 100, 80)))
+	require.NoError(t, err)
+
+	body, contentType := multipartImages(t, map[string][]byte{"icon.png": icon.Bytes()})
+	r := httptest.NewRequest(http.MethodPost, "/api/products/1/images", body)
+	r.Header.Set("Content-Type", contentType)
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.uploadProductImages(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	_, err = srv.GenerateImageRenditions(ctx)
+	require.NoError(t, err)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	require.Len(t, product.Images, 1)
+	srcset := product.Images[0].Srcset
+	require.NotEmpty(t, srcset)
+	for _, rendition := range srcset {
+		assert.Equal(t, 100, rendition.Width)
+	}
+}
+
+func TestRenditionKey(t *testing.T) {
+	assert.Equal(t, "3f2a-400w.webp", renditionKey("3f2a.png", 400, imaging.WebP))
+	assert.Equal(t, "3f2a-150w.jpg", renditionKey("3f2a.jpg", 150, imaging.JPEG))
+}
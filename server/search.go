@@ -0,0 +1,98 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// productSearchParams are the query parameters accepted by GET /api/products/search
+var productSearchParams = []string{"q", "limit", "currency"}
+
+// productSuggestParams are the query parameters accepted by GET /api/products/suggest
+var productSuggestParams = []string{"prefix", "limit"}
+
+// maxSuggestLimit caps how many suggestions a single request can ask for
+const maxSuggestLimit = 25
+
+func (s *Server) searchProducts(w http.ResponseWriter, r *http.Request) {
+	if err := checkQueryParams(r, productSearchParams); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	currency, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.db.SearchProducts(r.Context(), query, page.Limit)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if results == nil {
+		results = []db.SearchResult{}
+	}
+	refs := make([]*db.Product, len(results))
+	for i := range results {
+		refs[i] = &results[i].Product
+	}
+	if err := s.db.ConvertPrices(r.Context(), currency, refs); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listResponse[db.SearchResult]{Items: results})
+}
+
+func (s *Server) suggestProducts(w http.ResponseWriter, r *http.Request) {
+	if err := checkQueryParams(r, productSuggestParams); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	prefix := strings.TrimSpace(query.Get("prefix"))
+	if prefix == "" {
+		http.Error(w, "prefix is required", http.StatusBadRequest)
+		return
+	}
+	limit := db.DefaultSuggestLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSuggestLimit {
+			http.Error(w, fmt.Sprintf("limit must be an integer between 1 and %d", maxSuggestLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	suggestions, err := s.db.SuggestProducts(r.Context(), prefix, s.cfg.Search.MinSimilarity, limit)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if suggestions == nil {
+		suggestions = []db.Suggestion{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listResponse[db.Suggestion]{Items: suggestions})
+}
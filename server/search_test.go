@@ -0,0 +1,78 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/money"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchProducts(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Oak Desk", Price: money.MustParse("199.99", "USD"), Image: "https://via.placeholder.com/150", Description: "A sturdy desk made from solid oak"},
+		{ID: 2, Name: "Bookshelf", Price: money.MustParse("89.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Five shelves of pine"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/search?q=oak", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res listResponse[db.SearchResult]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.Len(t, res.Items, 1)
+	validateProduct(t, res.Items[0].Product, testProducts[0])
+	assert.Contains(t, res.Items[0].Snippet, "<mark>oak</mark>")
+	assert.Equal(t, "<mark>Oak</mark> Desk", res.Items[0].NameHighlight)
+
+	for _, query := range []string{"", "q=", "q=oak&sort=price"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/products/search?"+query, nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestSuggestProducts(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Desk Lamp", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Bookshelf", Price: money.MustParse("89.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/suggest?prefix=des", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res listResponse[db.Suggestion]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Items)
+	assert.Equal(t, int64(1), res.Items[0].ID)
+	assert.Equal(t, "Desk Lamp", res.Items[0].Name)
+
+	for _, query := range []string{"", "prefix=des&limit=0", "prefix=des&limit=100", "prefix=des&q=x"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/products/suggest?"+query, nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -3,12 +3,17 @@ package server
 import (
 	"catalogapi/config"
 	"catalogapi/db"
+	"catalogapi/money"
+	"catalogapi/storage"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/auth"
@@ -21,22 +26,41 @@ type contextKey string
 // Define key constants
 const userIDKey contextKey = "userID"
 
+// adminClaim is the Firebase custom claim that marks a user as an admin
+const adminClaim = "admin"
+
+var errNotAdmin = errors.New("admin privileges required")
+
 // Server represents the HTTP server and its dependencies
 type Server struct {
-	router http.Handler
-	db     *db.DB
-	auth   *auth.Client
+	router  http.Handler
+	db      *db.DB
+	auth    *auth.Client
+	cfg     *config.Config
+	store   storage.BlobStore
+	locales *locales
 }
 
 // New creates a new server instance with all required dependencies
 func New(database *db.DB) *Server {
-	auth, err := newAuthClient(context.Background())
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	auth, err := newAuthClient(context.Background(), cfg.Firebase)
 	if err != nil {
 		log.Fatalf("Failed to create auth client: %v", err)
 	}
+	locales, err := newLocales(cfg.Locale)
+	if err != nil {
+		log.Fatalf("Failed to load locales: %v", err)
+	}
 	s := &Server{
-		db:   database,
-		auth: auth,
+		db:      database,
+		auth:    auth,
+		cfg:     cfg,
+		store:   storage.NewLocalStore(cfg.Storage.Dir),
+		locales: locales,
 	}
 	s.setupRoutes()
 	return s
@@ -54,13 +78,32 @@ func authMiddleware(auth *auth.Client, next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// adminMiddleware only lets through users whose token carries the admin claim
+func adminMiddleware(auth *auth.Client, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := authorizeAdmin(auth, r)
+		if errors.Is(err, errNotAdmin) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
 // corsMiddleware adds CORS headers to allow requests from localhost:3000
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers for ALL requests
 		w.Header().Set("Access-Control-Allow-Origin", "https://linnovate-assignment-web.vercel.app")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		// Link carries the next page of listings and is not readable cross-origin otherwise
+		w.Header().Set("Access-Control-Expose-Headers", "Link")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 
@@ -81,8 +124,64 @@ func (s *Server) setupRoutes() {
 
 	// Add routes with and without trailing slash
 	mux.HandleFunc("GET /api/products", s.getProducts)
+	mux.HandleFunc("GET /api/products/search", s.searchProducts)
+	mux.HandleFunc("GET /api/products/suggest", s.suggestProducts)
+	mux.HandleFunc("GET /api/products/facets", s.getProductFacets)
+	mux.HandleFunc("GET /api/products/{id}", s.getProduct)
+	mux.HandleFunc("POST /api/products", adminMiddleware(s.auth, s.createProduct))
+	mux.HandleFunc("PUT /api/products/{id}", adminMiddleware(s.auth, s.updateProduct))
+	mux.HandleFunc("PATCH /api/products/{id}", adminMiddleware(s.auth, s.patchProduct))
+	mux.HandleFunc("DELETE /api/products/{id}", adminMiddleware(s.auth, s.deleteProduct))
+	mux.HandleFunc("POST /api/products/{id}/restore", adminMiddleware(s.auth, s.restoreProduct))
 	mux.HandleFunc("POST /api/reviews", authMiddleware(s.auth, s.postReview))
+	mux.HandleFunc("PUT /api/reviews/{id}", authMiddleware(s.auth, s.updateReview))
+	mux.HandleFunc("DELETE /api/reviews/{id}", authMiddleware(s.auth, s.deleteReview))
 	mux.HandleFunc("GET /api/products/{id}/reviews", s.getProductReviews)
+	mux.HandleFunc("PUT /api/products/{id}/reviews/me", authMiddleware(s.auth, s.upsertMyReview))
+	mux.HandleFunc("GET /api/products/{id}/rating-summary", s.getRatingSummary)
+	mux.HandleFunc("GET /api/products/{id}/related", s.getRelatedProducts)
+	mux.HandleFunc("GET /api/products/{id}/translations", adminMiddleware(s.auth, s.getProductTranslations))
+	mux.HandleFunc("PUT /api/products/{id}/translations/{locale}", adminMiddleware(s.auth, s.setProductTranslation))
+	mux.HandleFunc("DELETE /api/products/{id}/translations/{locale}", adminMiddleware(s.auth, s.deleteProductTranslation))
+	mux.HandleFunc("PUT /api/products/{id}/categories", adminMiddleware(s.auth, s.setProductCategories))
+	mux.HandleFunc("POST /api/products/{id}/variants", adminMiddleware(s.auth, s.createVariant))
+	mux.HandleFunc("PUT /api/products/{id}/variants/{variantId}", adminMiddleware(s.auth, s.updateVariant))
+	mux.HandleFunc("DELETE /api/products/{id}/variants/{variantId}", adminMiddleware(s.auth, s.deleteVariant))
+	mux.HandleFunc("PUT /api/products/{id}/stock", adminMiddleware(s.auth, s.setStock))
+	mux.HandleFunc("POST /api/products/{id}/images", adminMiddleware(s.auth, s.uploadProductImages))
+	mux.HandleFunc("DELETE /api/products/{id}/images/{imageId}", adminMiddleware(s.auth, s.deleteProductImage))
+	mux.HandleFunc("GET /api/products/{id}/prices", s.getProductPrices)
+	mux.HandleFunc("PUT /api/products/{id}/prices", adminMiddleware(s.auth, s.setProductPrice))
+	mux.HandleFunc("DELETE /api/products/{id}/prices/{currency}", adminMiddleware(s.auth, s.deleteProductPrice))
+	mux.HandleFunc("GET /api/products/{id}/price-history", adminMiddleware(s.auth, s.getPriceHistory))
+	mux.HandleFunc("GET /api/products/{id}/scheduled-prices", adminMiddleware(s.auth, s.getScheduledPriceChanges))
+	mux.HandleFunc("POST /api/products/{id}/scheduled-prices", adminMiddleware(s.auth, s.createScheduledPriceChange))
+	mux.HandleFunc("DELETE /api/products/{id}/scheduled-prices/{changeId}", adminMiddleware(s.auth, s.cancelScheduledPriceChange))
+
+	mux.HandleFunc("GET /api/exchange-rates", s.getExchangeRates)
+	mux.HandleFunc("PUT /api/exchange-rates/{currency}", adminMiddleware(s.auth, s.setExchangeRate))
+	mux.HandleFunc("DELETE /api/exchange-rates/{currency}", adminMiddleware(s.auth, s.deleteExchangeRate))
+
+	mux.HandleFunc("POST /api/reservations", authMiddleware(s.auth, s.reserveStock))
+	mux.HandleFunc("POST /api/reservations/{id}/commit", authMiddleware(s.auth, s.commitReservation))
+	mux.HandleFunc("DELETE /api/reservations/{id}", authMiddleware(s.auth, s.releaseReservation))
+
+	mux.HandleFunc("GET "+imagesPath+"{key}", s.serveImage)
+
+	mux.HandleFunc("GET /api/admin/products", adminMiddleware(s.auth, s.getAdminProducts))
+	mux.HandleFunc("POST /api/admin/products/import", adminMiddleware(s.auth, s.importProducts))
+	mux.HandleFunc("GET /api/admin/products/export", adminMiddleware(s.auth, s.exportProducts))
+	mux.HandleFunc("GET /api/admin/reviews", adminMiddleware(s.auth, s.getReviewQueue))
+	mux.HandleFunc("POST /api/admin/reviews/approve", adminMiddleware(s.auth, s.approveReviews))
+	mux.HandleFunc("POST /api/admin/reviews/reject", adminMiddleware(s.auth, s.rejectReviews))
+	mux.HandleFunc("POST /api/admin/reviews/{id}/approve", adminMiddleware(s.auth, s.approveReview))
+	mux.HandleFunc("POST /api/admin/reviews/{id}/reject", adminMiddleware(s.auth, s.rejectReview))
+
+	mux.HandleFunc("GET /api/categories", s.getCategoryTree)
+	mux.HandleFunc("GET /api/categories/{id}/products", s.getCategoryProducts)
+	mux.HandleFunc("POST /api/categories", adminMiddleware(s.auth, s.createCategory))
+	mux.HandleFunc("PUT /api/categories/{id}", adminMiddleware(s.auth, s.updateCategory))
+	mux.HandleFunc("DELETE /api/categories/{id}", adminMiddleware(s.auth, s.deleteCategory))
 
 	// Add CORS middleware to the router
 	s.router = corsMiddleware(mux)
@@ -95,15 +194,237 @@ func (s *Server) Handler() http.Handler {
 }
 
 func (s *Server) getProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := s.db.GetProducts(r.Context())
+	s.listProducts(w, r, productListParams)
+}
+
+// getAdminProducts is the product listing of the admin, which takes an
+// include_archived flag on top of the public parameters
+func (s *Server) getAdminProducts(w http.ResponseWriter, r *http.Request) {
+	s.listProducts(w, r, adminProductListParams)
+}
+
+func (s *Server) listProducts(w http.ResponseWriter, r *http.Request, params []string) {
+	filter, err := parseProductFilter(r, params)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.writeProductList(w, r, filter)
+}
 
+// writeProductList writes one page of the products matching filter, priced
+// and localized as the request asks. Every product listing goes through it.
+func (s *Server) writeProductList(w http.ResponseWriter, r *http.Request, filter db.ProductFilter) {
+	page, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	currency, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locale, err := s.locales.negotiate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	products, next, err := s.db.GetProducts(r.Context(), filter, page)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if products == nil {
+		products = []db.Product{}
+	}
+	if err := s.convertPrices(r, currency, products); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	refs := make([]*db.Product, len(products))
+	for i := range products {
+		refs[i] = &products[i]
+	}
+	if err := s.localize(w, r, locale, refs); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	setNextLink(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(listResponse[db.Product]{Items: products, NextCursor: next})
+}
+
+func (s *Server) getProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	currency, err := parseCurrency(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locale, err := s.locales.negotiate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	product, err := s.db.GetProduct(r.Context(), id)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	product.Variants, err = s.db.GetProductVariants(r.Context(), id)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if err := s.attachImages(r, &product); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if err := s.db.ConvertPrices(r.Context(), currency, []*db.Product{&product}); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if err := s.localize(w, r, locale, []*db.Product{&product}); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+func (s *Server) createProduct(w http.ResponseWriter, r *http.Request) {
+	var clientProduct db.ClientProduct
+	if err := decodeJSON(r, &clientProduct); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientProduct(clientProduct); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	product, err := s.db.CreateProduct(r.Context(), clientProduct)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(product)
+}
+
+func (s *Server) updateProduct(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var clientProduct db.ClientProduct
+	if err := decodeJSON(r, &clientProduct); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientProduct(clientProduct); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	product, err := s.db.UpdateProduct(r.Context(), id, clientProduct, userID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+func (s *Server) patchProduct(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var patch db.ProductPatch
+	if err := decodeJSON(r, &patch); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateProductPatch(patch); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	product, err := s.db.PatchProduct(r.Context(), id, patch, userID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// deleteProduct archives the product rather than removing it, so its
+// reviews and images stay around and it can be restored
+func (s *Server) deleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.ArchiveProduct(r.Context(), id); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) restoreProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	product, err := s.db.RestoreProduct(r.Context(), id)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
 }
 
 func (s *Server) postReview(w http.ResponseWriter, r *http.Request) {
@@ -114,31 +435,137 @@ func (s *Server) postReview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var clientReview db.ClientReview
-	err := json.NewDecoder(r.Body).Decode(&clientReview)
+	if err := decodeJSON(r, &clientReview); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientReview(clientReview); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	status := s.newReviewStatus()
+	review, err := s.db.PostReview(r.Context(), clientReview, userId, status)
+	var exists *db.ReviewExistsError
+	if errors.As(err, &exists) {
+		// Point the client at the review to edit instead
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(reviewConflict{Error: err.Error(), ReviewID: exists.ReviewID})
+		return
+	}
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	safeReview := toSafeReview(review)
+	safeReview.Status = status
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(safeReview)
+}
+
+// upsertMyReview creates the caller's review of a product, or replaces it
+// if they already reviewed the product
+func (s *Server) upsertMyReview(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	productId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	review, err := s.db.PostReview(r.Context(), clientReview, userId)
+	var edit db.ReviewEdit
+	if err := decodeJSON(r, &edit); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateReviewEdit(edit); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	status := s.newReviewStatus()
+	review, created, err := s.db.UpsertReview(r.Context(), productId, userId, edit, status)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, err)
 		return
 	}
 
-	safeReview := db.SafeReview{
-		ID:            review.ID,
-		ProductID:     clientReview.ProductID,
-		ReviewTitle:   clientReview.ReviewTitle,
-		ReviewContent: clientReview.ReviewContent,
-		Stars:         clientReview.Stars,
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	safeReview := toSafeReview(review)
+	safeReview.Status = status
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(safeReview)
+}
+
+// updateReview lets the author of a review rewrite it
+func (s *Server) updateReview(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var edit db.ReviewEdit
+	if err := decodeJSON(r, &edit); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateReviewEdit(edit); err != nil {
+		writeRequestError(w, err)
+		return
 	}
 
+	status := s.newReviewStatus()
+	review, err := s.db.UpdateReview(r.Context(), id, userId, edit, status)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	safeReview := toSafeReview(review)
+	safeReview.Status = status
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(safeReview)
 }
 
+// deleteReview lets the author of a review take it down
+func (s *Server) deleteReview(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(userIDKey).(string)
+	if !ok {
+		http.Error(w, "user not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteReview(r.Context(), id, userId); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (s *Server) getProductReviews(w http.ResponseWriter, r *http.Request) {
 	productId := r.PathValue("id")
 	if productId == "" {
@@ -150,38 +577,34 @@ func (s *Server) getProductReviews(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	reviews, err := s.db.GetProductReviews(r.Context(), productIdInt)
+	page, err := parsePage(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reviews, next, err := s.db.GetProductReviews(r.Context(), productIdInt, page)
+	if err != nil {
+		writeDBError(w, err)
 		return
 	}
 
 	safeReviews := make([]db.SafeReview, len(reviews))
 	for i, review := range reviews {
-		safeReviews[i] = db.SafeReview{
-			ID:            review.ID,
-			ProductID:     review.ProductID,
-			ReviewTitle:   review.ReviewTitle,
-			ReviewContent: review.ReviewContent,
-			Stars:         review.Stars,
-		}
+		safeReviews[i] = toSafeReview(review)
 	}
 
+	setNextLink(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(safeReviews)
+	json.NewEncoder(w).Encode(listResponse[db.SafeReview]{Items: safeReviews, NextCursor: next})
 }
 
 // ===========================================
 // =================HELPERS===================
 // ===========================================
 
-func newAuthClient(ctx context.Context) (*auth.Client, error) {
-	cfg, err := config.Load()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
-	}
-	opt := option.WithCredentialsFile(cfg.Firebase.CredentialsFile)
+func newAuthClient(ctx context.Context, cfg config.FirebaseConfig) (*auth.Client, error) {
+	opt := option.WithCredentialsFile(cfg.CredentialsFile)
 	app, err := firebase.NewApp(ctx, nil, opt)
 	if err != nil {
 		return nil, err
@@ -208,3 +631,103 @@ func authorize(auth *auth.Client, r *http.Request) (string, error) {
 
 	return u.UID, nil
 }
+
+func authorizeAdmin(auth *auth.Client, r *http.Request) (string, error) {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		return "", fmt.Errorf("missing Authorization header")
+	}
+	ctx := context.Background()
+	u, err := auth.VerifyIDToken(ctx, token)
+	if err != nil {
+		return "", fmt.Errorf("invalid Authorization header: %w", err)
+	}
+	if isAdmin, _ := u.Claims[adminClaim].(bool); !isAdmin {
+		return "", errNotAdmin
+	}
+
+	return u.UID, nil
+}
+
+// convertPrices rewrites the prices of a product listing in currency
+func (s *Server) convertPrices(r *http.Request, currency string, products []db.Product) error {
+	refs := make([]*db.Product, len(products))
+	for i := range products {
+		refs[i] = &products[i]
+	}
+	return s.db.ConvertPrices(r.Context(), currency, refs)
+}
+
+// writeDBError maps db sentinel errors onto HTTP status codes
+func writeDBError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, db.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, db.ErrConflict), errors.Is(err, db.ErrInsufficientStock):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, db.ErrInvalidCursor), errors.Is(err, db.ErrInvalidFilter), errors.Is(err, db.ErrUnsupportedCurrency):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// reviewConflict is the body of the 409 returned when a user posts a second
+// review of the same product
+type reviewConflict struct {
+	Error    string `json:"error"`
+	ReviewID int64  `json:"reviewId"`
+}
+
+// toSafeReview leaves out the author of a review, which is not shown publicly
+func toSafeReview(review db.Review) db.SafeReview {
+	return db.SafeReview{
+		ID:            review.ID,
+		ProductID:     review.ProductID,
+		VariantID:     review.VariantID,
+		ReviewTitle:   review.ReviewTitle,
+		ReviewContent: review.ReviewContent,
+		Stars:         review.Stars,
+		UpdatedAt:     review.UpdatedAt,
+	}
+}
+
+func validateClientProduct(p db.ClientProduct) error {
+	var v validator
+	v.required("name", p.Name)
+	v.price("price", p.Price)
+	v.imageURL("image", p.Image)
+	v.attributes("attributes", p.Attributes)
+	return v.err()
+}
+
+func validateProductPatch(p db.ProductPatch) error {
+	var v validator
+	if p.Name != nil {
+		v.check(strings.TrimSpace(*p.Name) != "", "name", "must not be empty")
+	}
+	if p.Price != nil {
+		v.price("price", *p.Price)
+	}
+	if p.Image != nil {
+		v.imageURL("image", *p.Image)
+	}
+	if p.Attributes != nil {
+		v.attributes("attributes", *p.Attributes)
+	}
+	return v.err()
+}
+
+// price checks a catalog price, prices are stored in the default currency
+func (v *validator) price(field string, price money.Money) {
+	v.check(!price.IsNegative(), field, "must not be negative")
+	v.check(price.Currency() == money.DefaultCurrency, field, "currency must be "+money.DefaultCurrency)
+}
+
+// imageURL checks that a field holds an absolute http(s) URL
+func (v *validator) imageURL(field, image string) {
+	u, err := url.ParseRequestURI(image)
+	v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", field, "must be an absolute http(s) URL")
+}
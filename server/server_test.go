@@ -3,13 +3,16 @@ package server
 import (
 	"bytes"
 	"catalogapi/db"
+	"catalogapi/money"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,6 +23,8 @@ func setupTestDB(t *testing.T) (*db.DB, func(), context.Context) {
 	ctx := context.Background()
 
 	os.Setenv("APP_ENV", "test")
+	// Publish reviews right away unless a test sets up moderation
+	os.Setenv("REVIEWS_AUTO_APPROVE", "true")
 	database, err := db.NewTestDB(ctx)
 	require.NoError(t, err)
 
@@ -38,9 +43,9 @@ func TestGetProducts(t *testing.T) {
 	srv := New(database)
 
 	testProducts := []db.Product{
-		{ID: 1, Name: "Test Product 1", Price: 19.99, Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
-		{ID: 2, Name: "Test Product 2", Price: 29.99, Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
-		{ID: 3, Name: "Test Product 3", Price: 39.99, Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
 	}
 
 	err := db.PopulateTestData(ctx, database, "products", testProducts)
@@ -52,26 +57,172 @@ func TestGetProducts(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, w.Code)
 
-	var products []db.Product
-	err = json.NewDecoder(w.Body).Decode(&products)
+	var res listResponse[db.Product]
+	err = json.NewDecoder(w.Body).Decode(&res)
 	require.NoError(t, err)
 
-	require.Equal(t, len(testProducts), len(products))
+	require.Equal(t, len(testProducts), len(res.Items))
+	require.Empty(t, res.NextCursor)
 
-	for i, p := range products {
+	for i, p := range res.Items {
 		validateProduct(t, p, testProducts[i])
 	}
 }
 
+func TestGetProductsByRating(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []db.Review{
+		{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 3},
+		{ID: 2, UserId: "2", ProductID: 2, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 5},
+		{ID: 3, UserId: "3", ProductID: 2, ReviewTitle: "Title 3", ReviewContent: "Content 3", Stars: 4},
+	}
+	err = db.PopulateTestData(ctx, database, "reviews", testReviews)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products?sort=-rating", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res listResponse[db.Product]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.Len(t, res.Items, 2)
+	assert.Equal(t, int64(2), res.Items[0].ID)
+	assert.Equal(t, 4.5, res.Items[0].AverageRating)
+	assert.Equal(t, int64(2), res.Items[0].ReviewCount)
+	assert.Equal(t, 3.0, res.Items[1].AverageRating)
+}
+
+func TestGetProductsPagination(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	var all []db.Product
+	path := "/api/products?limit=2"
+	for path != "" {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res listResponse[db.Product]
+		err = json.NewDecoder(w.Body).Decode(&res)
+		require.NoError(t, err)
+		all = append(all, res.Items...)
+
+		path = ""
+		if res.NextCursor != "" {
+			link := w.Header().Get("Link")
+			require.Contains(t, link, `rel="next"`)
+			path = "/api/products?limit=2&cursor=" + res.NextCursor
+		}
+	}
+
+	require.Len(t, all, len(testProducts))
+	for i, p := range all {
+		validateProduct(t, p, testProducts[i])
+	}
+
+	for _, query := range []string{"limit=0", "limit=abc", "cursor=!!!"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/products?"+query, nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestGetProductsFiltered(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Desk Lamp", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Floor Lamp", Price: money.MustParse("59.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Desk", Price: money.MustParse("99.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products?q=lamp&sort=-price", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res listResponse[db.Product]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.Len(t, res.Items, 2)
+	validateProduct(t, res.Items[0], testProducts[1])
+	validateProduct(t, res.Items[1], testProducts[0])
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products?colour=red", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "allowed parameters are")
+}
+
+func TestGetProduct(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	for _, tp := range testProducts {
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/products/%d", tp.ID), nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var product db.Product
+		err = json.NewDecoder(w.Body).Decode(&product)
+		require.NoError(t, err)
+		validateProduct(t, product, tp)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/42", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestPostReview(t *testing.T) {
 	database, cleanup, ctx := setupTestDB(t)
 	defer cleanup()
 	srv := New(database)
 
 	testProducts := []db.Product{
-		{ID: 1, Name: "Test Product 1", Price: 19.99, Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
-		{ID: 2, Name: "Test Product 2", Price: 29.99, Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
-		{ID: 3, Name: "Test Product 3", Price: 39.99, Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
 	}
 
 	err := db.PopulateTestData(ctx, database, "products", testProducts)
@@ -110,26 +261,203 @@ func TestPostReview(t *testing.T) {
 	}
 }
 
+func TestPostReviewConflict(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []db.Review{
+		{ID: 7, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 3},
+	}
+	err = db.PopulateTestData(ctx, database, "reviews", testReviews)
+	require.NoError(t, err)
+
+	body := `{"productId": 1, "reviewTitle": "Title 2", "reviewContent": "Content 2", "stars": 4}`
+	r := httptest.NewRequest(http.MethodPost, "/api/reviews", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "1")
+	srv.postReview(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusConflict, w.Code)
+
+	var res struct {
+		ReviewID int64 `json:"reviewId"`
+	}
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), res.ReviewID)
+}
+
+func TestUpsertMyReview(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	upsert := func(productId, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPut, "/api/products/"+productId+"/reviews/me", bytes.NewBufferString(body))
+		r.SetPathValue("id", productId)
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "1")
+		srv.upsertMyReview(w, r.WithContext(authCtx))
+		return w
+	}
+
+	w := upsert("1", `{"reviewTitle": "Title 1", "reviewContent": "Content 1", "stars": 2}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created db.SafeReview
+	err = json.NewDecoder(w.Body).Decode(&created)
+	require.NoError(t, err)
+
+	w = upsert("1", `{"reviewTitle": "Title 2", "reviewContent": "Content 2", "stars": 5}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var replaced db.SafeReview
+	err = json.NewDecoder(w.Body).Decode(&replaced)
+	require.NoError(t, err)
+	validateSafeReview(t, replaced, db.Review{ID: created.ID, ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 5})
+
+	w = upsert("42", `{"reviewTitle": "Title", "reviewContent": "Content", "stars": 1}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUpdateReview(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []db.Review{
+		{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
+	}
+	err = db.PopulateTestData(ctx, database, "reviews", testReviews)
+	require.NoError(t, err)
+
+	// Warm the cached rating summary, the edit must drop it
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1/rating-summary", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	update := func(id, userId, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPut, "/api/reviews/"+id, bytes.NewBufferString(body))
+		r.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, userId)
+		srv.updateReview(w, r.WithContext(authCtx))
+		return w
+	}
+
+	w = update("1", "1", `{"reviewTitle": "Edited", "reviewContent": "Edited content", "stars": 4}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var review db.SafeReview
+	err = json.NewDecoder(w.Body).Decode(&review)
+	require.NoError(t, err)
+	validateSafeReview(t, review, db.Review{ID: 1, ProductID: 1, ReviewTitle: "Edited", ReviewContent: "Edited content", Stars: 4})
+	assert.WithinDuration(t, time.Now(), review.UpdatedAt, time.Minute)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products/1/rating-summary", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var summary db.RatingSummary
+	err = json.NewDecoder(w.Body).Decode(&summary)
+	require.NoError(t, err)
+	require.NotNil(t, summary.Mean)
+	assert.Equal(t, 4.0, *summary.Mean)
+
+	w = update("1", "2", `{"reviewTitle": "Hijacked", "reviewContent": "Hijacked", "stars": 1}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = update("42", "1", `{"reviewTitle": "Missing", "reviewContent": "Missing", "stars": 1}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = update("abc", "1", `{"reviewTitle": "Title", "reviewContent": "Content", "stars": 1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteReview(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	testReviews := []db.Review{
+		{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
+		{ID: 2, UserId: "2", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 5},
+	}
+	err = db.PopulateTestData(ctx, database, "reviews", testReviews)
+	require.NoError(t, err)
+
+	// Warm the cached rating summary, the delete must drop it
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1/rating-summary", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	remove := func(id, userId string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodDelete, "/api/reviews/"+id, nil)
+		r.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, userId)
+		srv.deleteReview(w, r.WithContext(authCtx))
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, remove("1", "2").Code)
+	assert.Equal(t, http.StatusNotFound, remove("42", "1").Code)
+	assert.Equal(t, http.StatusNoContent, remove("1", "1").Code)
+	assert.Equal(t, http.StatusNotFound, remove("1", "1").Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products/1/rating-summary", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var summary db.RatingSummary
+	err = json.NewDecoder(w.Body).Decode(&summary)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.Count)
+	assert.Equal(t, int64(0), summary.Histogram[1])
+}
+
 func TestGetProductReviews(t *testing.T) {
 	database, cleanup, ctx := setupTestDB(t)
 	defer cleanup()
 	srv := New(database)
 
 	testProducts := []db.Product{
-		{ID: 1, Name: "Test Product 1", Price: 19.99, Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
-		{ID: 2, Name: "Test Product 2", Price: 29.99, Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
-		{ID: 3, Name: "Test Product 3", Price: 39.99, Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+		{ID: 3, Name: "Test Product 3", Price: money.MustParse("39.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 3"},
 	}
 	err := db.PopulateTestData(ctx, database, "products", testProducts)
 	require.NoError(t, err)
 
 	testReviews := []db.Review{
 		{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
-		{ID: 2, UserId: "1", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
+		{ID: 2, UserId: "2", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
 		{ID: 3, UserId: "1", ProductID: 2, ReviewTitle: "Title 3", ReviewContent: "Content 3", Stars: 3},
-		{ID: 4, UserId: "1", ProductID: 2, ReviewTitle: "Title 4", ReviewContent: "Content 4", Stars: 1},
+		{ID: 4, UserId: "2", ProductID: 2, ReviewTitle: "Title 4", ReviewContent: "Content 4", Stars: 1},
 		{ID: 5, UserId: "1", ProductID: 3, ReviewTitle: "Title 5", ReviewContent: "Content 5", Stars: 2},
-		{ID: 6, UserId: "1", ProductID: 3, ReviewTitle: "Title 6", ReviewContent: "Content 6", Stars: 3},
+		{ID: 6, UserId: "2", ProductID: 3, ReviewTitle: "Title 6", ReviewContent: "Content 6", Stars: 3},
 	}
 	err = db.PopulateTestData(ctx, database, "reviews", testReviews)
 	require.NoError(t, err)
@@ -142,21 +470,21 @@ func TestGetProductReviews(t *testing.T) {
 			productId: 1,
 			expected: []db.Review{
 				{ID: 1, UserId: "1", ProductID: 1, ReviewTitle: "Title 1", ReviewContent: "Content 1", Stars: 1},
-				{ID: 2, UserId: "1", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
+				{ID: 2, UserId: "2", ProductID: 1, ReviewTitle: "Title 2", ReviewContent: "Content 2", Stars: 2},
 			},
 		},
 		{
 			productId: 2,
 			expected: []db.Review{
 				{ID: 3, UserId: "1", ProductID: 2, ReviewTitle: "Title 3", ReviewContent: "Content 3", Stars: 3},
-				{ID: 4, UserId: "1", ProductID: 2, ReviewTitle: "Title 4", ReviewContent: "Content 4", Stars: 1},
+				{ID: 4, UserId: "2", ProductID: 2, ReviewTitle: "Title 4", ReviewContent: "Content 4", Stars: 1},
 			},
 		},
 		{
 			productId: 3,
 			expected: []db.Review{
 				{ID: 5, UserId: "1", ProductID: 3, ReviewTitle: "Title 5", ReviewContent: "Content 5", Stars: 2},
-				{ID: 6, UserId: "1", ProductID: 3, ReviewTitle: "Title 6", ReviewContent: "Content 6", Stars: 3},
+				{ID: 6, UserId: "2", ProductID: 3, ReviewTitle: "Title 6", ReviewContent: "Content 6", Stars: 3},
 			},
 		},
 	}
@@ -169,10 +497,11 @@ func TestGetProductReviews(t *testing.T) {
 
 		require.Equal(t, http.StatusOK, w.Code)
 
-		var reviews []db.SafeReview
+		var res listResponse[db.SafeReview]
 
-		err := json.NewDecoder(w.Body).Decode(&reviews)
+		err := json.NewDecoder(w.Body).Decode(&res)
 		require.NoError(t, err)
+		reviews := res.Items
 
 		assert.Len(t, reviews, len(tt.expected))
 
@@ -181,6 +510,240 @@ func TestGetProductReviews(t *testing.T) {
 			validateSafeReview(t, r, tr)
 		}
 	}
+	r := httptest.NewRequest(http.MethodGet, "/api/products/42/reviews", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateProduct(t *testing.T) {
+	database, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	tests := []struct {
+		product  db.ClientProduct
+		expected int
+	}{
+		{product: db.ClientProduct{Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"}, expected: http.StatusCreated},
+		{product: db.ClientProduct{Name: "", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150"}, expected: http.StatusUnprocessableEntity},
+		{product: db.ClientProduct{Name: "Negative", Price: money.MustParse("-1", "USD"), Image: "https://via.placeholder.com/150"}, expected: http.StatusUnprocessableEntity},
+		{product: db.ClientProduct{Name: "Bad Image", Price: money.MustParse("1", "USD"), Image: "not a url"}, expected: http.StatusUnprocessableEntity},
+		{product: db.ClientProduct{Name: "Euro", Price: money.MustParse("1", "EUR"), Image: "https://via.placeholder.com/150"}, expected: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		jsonData, err := json.Marshal(tt.product)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/api/products", bytes.NewBuffer(jsonData))
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+		srv.createProduct(w, r.WithContext(authCtx))
+
+		require.Equal(t, tt.expected, w.Code)
+		if tt.expected != http.StatusCreated {
+			continue
+		}
+
+		var product db.Product
+		err = json.NewDecoder(w.Body).Decode(&product)
+		require.NoError(t, err)
+		assert.NotZero(t, product.ID)
+		assert.Equal(t, tt.product.Name, product.Name)
+		assert.Equal(t, tt.product.Price, product.Price)
+		assert.Equal(t, tt.product.Image, product.Image)
+		assert.Equal(t, tt.product.Description, product.Description)
+	}
+}
+
+func TestUpdateProduct(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	expected := db.Product{ID: 1, Name: "Updated Product", Price: money.MustParse("24.99", "USD"), Image: "https://via.placeholder.com/300", Description: "Updated Description"}
+	jsonData, err := json.Marshal(db.ClientProduct{Name: expected.Name, Price: expected.Price, Image: expected.Image, Description: expected.Description})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/products/1", bytes.NewBuffer(jsonData))
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.updateProduct(w, r.WithContext(authCtx))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	validateProduct(t, product, expected)
+}
+
+func TestPatchProduct(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPatch, "/api/products/1", bytes.NewBufferString(`{"price": {"amount": "14.99", "currency": "USD"}}`))
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.patchProduct(w, r.WithContext(authCtx))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+
+	expected := testProducts[0]
+	expected.Price = money.MustParse("14.99", "USD")
+	validateProduct(t, product, expected)
+}
+
+func TestDeleteProduct(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/products/1", nil)
+	r.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	srv.deleteProduct(w, r)
+
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	// The archived product is still readable by id
+	r = httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	assert.NotNil(t, product.ArchivedAt)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/products", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res listResponse[db.Product]
+	err = json.NewDecoder(w.Body).Decode(&res)
+	require.NoError(t, err)
+	require.Len(t, res.Items, 1)
+	assert.Equal(t, int64(2), res.Items[0].ID)
+
+	r = httptest.NewRequest(http.MethodDelete, "/api/products/42", nil)
+	r.SetPathValue("id", "42")
+	w = httptest.NewRecorder()
+	srv.deleteProduct(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRestoreProduct(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	archivedAt := time.Now()
+	testProducts := []db.Product{
+		{ID: 1, Name: "Test Product 1", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+		{ID: 2, Name: "Test Product 2", Price: money.MustParse("29.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 2", ArchivedAt: &archivedAt},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	listIDs := func(query string) []int64 {
+		r := httptest.NewRequest(http.MethodGet, "/api/admin/products"+query, nil)
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+		srv.getAdminProducts(w, r.WithContext(authCtx))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var res listResponse[db.Product]
+		err := json.NewDecoder(w.Body).Decode(&res)
+		require.NoError(t, err)
+		var ids []int64
+		for _, p := range res.Items {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{1}, listIDs(""))
+	assert.Equal(t, []int64{1, 2}, listIDs("?include_archived=true"))
+
+	r := httptest.NewRequest(http.MethodPost, "/api/products/2/restore", nil)
+	r.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+	authCtx := context.WithValue(r.Context(), userIDKey, "admin")
+	srv.restoreProduct(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	assert.Nil(t, product.ArchivedAt)
+	assert.Equal(t, []int64{1, 2}, listIDs(""))
+
+	r = httptest.NewRequest(http.MethodPost, "/api/products/42/restore", nil)
+	r.SetPathValue("id", "42")
+	w = httptest.NewRecorder()
+	srv.restoreProduct(w, r.WithContext(authCtx))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("preflight requests must not reach the handler")
+	}))
+
+	r := httptest.NewRequest(http.MethodOptions, "/api/products/1", nil)
+	r.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	methods := strings.Split(w.Header().Get("Access-Control-Allow-Methods"), ", ")
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		assert.Contains(t, methods, method)
+	}
+}
+
+func TestCORSExposesLink(t *testing.T) {
+	handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setNextLink(w, r, "next-cursor")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products?limit=2", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Link"), `rel="next"`)
+	assert.Equal(t, "Link", w.Header().Get("Access-Control-Expose-Headers"))
 }
 
 // ===========================================
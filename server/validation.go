@@ -0,0 +1,146 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on review payloads, review_title is a VARCHAR(255)
+const (
+	maxReviewTitleLength   = 255
+	maxReviewContentLength = 5000
+	minStars               = 1
+	maxStars               = 5
+)
+
+// fieldError describes why a single field of a request body is invalid
+type fieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// validationError lists every invalid field of a request body, it is written
+// out as a 422
+type validationError struct {
+	Errors []fieldError `json:"errors"`
+}
+
+// Error lists the invalid fields as prose, it is what an import report shows
+// for a row that fails validation
+func (e *validationError) Error() string {
+	reasons := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		reasons[i] = fe.Field + " " + fe.Reason
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// validator collects field errors, so a client learns about every invalid
+// field of a request in one go
+type validator struct {
+	errs []fieldError
+}
+
+// check records reason against field unless ok holds
+func (v *validator) check(ok bool, field, reason string) {
+	if !ok {
+		v.errs = append(v.errs, fieldError{Field: field, Reason: reason})
+	}
+}
+
+// required checks that a text field is not blank
+func (v *validator) required(field, value string) {
+	v.check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// maxLength checks that a text field has at most max characters
+func (v *validator) maxLength(field, value string, max int) {
+	v.check(utf8.RuneCountInString(value) <= max, field, fmt.Sprintf("must be at most %d characters", max))
+}
+
+// wholeInRange checks that a number is a whole number within [min, max]
+func (v *validator) wholeInRange(field string, value float64, min, max int) {
+	v.check(value == math.Trunc(value) && value >= float64(min) && value <= float64(max),
+		field, fmt.Sprintf("must be a whole number between %d and %d", min, max))
+}
+
+// err returns the collected field errors, or nil if there are none
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &validationError{Errors: v.errs}
+}
+
+// decodeJSON decodes a request body into dst, rejecting fields dst does not
+// have. Unknown fields and values of the wrong type are reported as a
+// validationError, a malformed body as a plain error.
+func decodeJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err == nil {
+		if dec.More() {
+			return fmt.Errorf("request body must contain a single JSON object")
+		}
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &validationError{Errors: []fieldError{{Field: typeErr.Field, Reason: "must be a " + typeErr.Type.String()}}}
+	}
+	// The json package has no error type for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &validationError{Errors: []fieldError{{Field: strings.Trim(field, `"`), Reason: "is not a known field"}}}
+	}
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("request body is required")
+	}
+	return err
+}
+
+// writeRequestError writes out an error from decoding or validating a request
+// body, as a 422 listing the invalid fields or as a 400
+func writeRequestError(w http.ResponseWriter, err error) {
+	var invalid *validationError
+	if errors.As(err, &invalid) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(invalid)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func validateClientReview(review db.ClientReview) error {
+	var v validator
+	v.check(review.ProductID > 0, "productId", "is required")
+	validateReviewFields(&v, review.VariantID, review.ReviewTitle, review.ReviewContent, review.Stars)
+	return v.err()
+}
+
+func validateReviewEdit(edit db.ReviewEdit) error {
+	var v validator
+	validateReviewFields(&v, edit.VariantID, edit.ReviewTitle, edit.ReviewContent, edit.Stars)
+	return v.err()
+}
+
+// validateReviewFields checks the fields shared by every review payload
+func validateReviewFields(v *validator, variantId *int64, title, content string, stars float64) {
+	if variantId != nil {
+		v.check(*variantId > 0, "variantId", "must be a positive id")
+	}
+	v.required("reviewTitle", title)
+	v.maxLength("reviewTitle", title, maxReviewTitleLength)
+	v.required("reviewContent", content)
+	v.maxLength("reviewContent", content, maxReviewContentLength)
+	v.wholeInRange("stars", stars, minStars, maxStars)
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"catalogapi/db"
+	"catalogapi/money"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateClientReview(t *testing.T) {
+	valid := db.ClientReview{ProductID: 1, ReviewTitle: "Title", ReviewContent: "Content", Stars: 4}
+	require.NoError(t, validateClientReview(valid))
+
+	variantId := int64(0)
+	invalid := db.ClientReview{VariantID: &variantId, ReviewTitle: " ", ReviewContent: strings.Repeat("a", maxReviewContentLength+1), Stars: 4.5}
+	err := validateClientReview(invalid)
+	var verr *validationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []fieldError{
+		{Field: "productId", Reason: "is required"},
+		{Field: "variantId", Reason: "must be a positive id"},
+		{Field: "reviewTitle", Reason: "is required"},
+		{Field: "reviewContent", Reason: "must be at most 5000 characters"},
+		{Field: "stars", Reason: "must be a whole number between 1 and 5"},
+	}, verr.Errors)
+
+	for _, stars := range []float64{0, 6, -1} {
+		err := validateReviewEdit(db.ReviewEdit{ReviewTitle: "Title", ReviewContent: "Content", Stars: stars})
+		require.ErrorAs(t, err, &verr, stars)
+		assert.Equal(t, "stars", verr.Errors[0].Field)
+	}
+
+	// Lengths count characters rather than bytes
+	title := strings.Repeat("é", maxReviewTitleLength)
+	require.NoError(t, validateReviewEdit(db.ReviewEdit{ReviewTitle: title, ReviewContent: "Content", Stars: 1}))
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		body  string
+		field string
+	}{
+		{body: `{"productId": 1, "rating": 5}`, field: "rating"},
+		{body: `{"productId": "one"}`, field: "productId"},
+		{body: `{"stars": [5]}`, field: "stars"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/reviews", strings.NewReader(tt.body))
+		var review db.ClientReview
+		err := decodeJSON(r, &review)
+		var verr *validationError
+		require.ErrorAs(t, err, &verr, tt.body)
+		require.Len(t, verr.Errors, 1)
+		assert.Equal(t, tt.field, verr.Errors[0].Field, tt.body)
+	}
+
+	for _, body := range []string{``, `{"productId": 1`, `{} {}`} {
+		r := httptest.NewRequest(http.MethodPost, "/api/reviews", strings.NewReader(body))
+		var review db.ClientReview
+		err := decodeJSON(r, &review)
+		require.Error(t, err, body)
+		var verr *validationError
+		assert.False(t, errors.As(err, &verr), body)
+	}
+}
+
+func TestPostReviewInvalid(t *testing.T) {
+	// Invalid reviews are turned away before the database is reached
+	srv := &Server{}
+
+	tests := []struct {
+		body     string
+		expected int
+		fields   []string
+	}{
+		{body: `{"productId": 1, "reviewTitle": "Title", "reviewContent": "Content", "stars": 9}`, expected: http.StatusUnprocessableEntity, fields: []string{"stars"}},
+		{body: `{"productId": 1, "reviewContent": "", "stars": 3}`, expected: http.StatusUnprocessableEntity, fields: []string{"reviewTitle", "reviewContent"}},
+		{body: `{"productId": 1, "reviewTitle": "Title", "reviewContent": "Content", "stars": 3, "userId": "admin"}`, expected: http.StatusUnprocessableEntity, fields: []string{"userId"}},
+		{body: `not json`, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/reviews", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "1")
+		srv.postReview(w, r.WithContext(authCtx))
+		require.Equal(t, tt.expected, w.Code, tt.body)
+		if tt.expected != http.StatusUnprocessableEntity {
+			continue
+		}
+
+		var res validationError
+		err := json.NewDecoder(w.Body).Decode(&res)
+		require.NoError(t, err)
+		var fields []string
+		for _, fe := range res.Errors {
+			fields = append(fields, fe.Field)
+			assert.NotEmpty(t, fe.Reason)
+		}
+		assert.Equal(t, tt.fields, fields, tt.body)
+	}
+}
+
+func TestValidateClientProduct(t *testing.T) {
+	product := db.ClientProduct{
+		Name:       " ",
+		Price:      money.MustParse("-1", "EUR"),
+		Image:      "not a url",
+		Attributes: db.Attributes{"brand": " "},
+	}
+	err := validateClientProduct(product)
+	var verr *validationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []fieldError{
+		{Field: "name", Reason: "is required"},
+		{Field: "price", Reason: "must not be negative"},
+		{Field: "price", Reason: "currency must be USD"},
+		{Field: "image", Reason: "must be an absolute http(s) URL"},
+		{Field: "attributes.brand", Reason: "is required"},
+	}, verr.Errors)
+	// Import reports show the errors as text
+	assert.Equal(t, "name is required; price must not be negative; price currency must be USD; image must be an absolute http(s) URL; attributes.brand is required", err.Error())
+
+	name := ""
+	err = validateProductPatch(db.ProductPatch{Name: &name})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []fieldError{{Field: "name", Reason: "must not be empty"}}, verr.Errors)
+	require.NoError(t, validateProductPatch(db.ProductPatch{}))
+}
+
+func TestCreateProductInvalid(t *testing.T) {
+	// Invalid products are turned away before the database is reached
+	srv := &Server{}
+
+	tests := []struct {
+		body   string
+		fields []string
+	}{
+		{body: `{"name": "", "price": {"amount": "1.00", "currency": "USD"}, "image": "ftp://example.com/a.png"}`, fields: []string{"name", "image"}},
+		{body: `{"name": "Desk", "price": {"amount": "1.00", "currency": "USD"}, "image": "https://via.placeholder.com/150", "stock": 5}`, fields: []string{"stock"}},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/products", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		srv.createProduct(w, r)
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code, tt.body)
+
+		var res validationError
+		err := json.NewDecoder(w.Body).Decode(&res)
+		require.NoError(t, err)
+		var fields []string
+		for _, fe := range res.Errors {
+			fields = append(fields, fe.Field)
+		}
+		assert.Equal(t, tt.fields, fields, tt.body)
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"catalogapi/db"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// skuPattern matches the SKU codes accepted for variants
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+func (s *Server) createVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var clientVariant db.ClientVariant
+	if err := decodeJSON(r, &clientVariant); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientVariant(clientVariant); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	variant, err := s.db.CreateVariant(r.Context(), productID, clientVariant)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(variant)
+}
+
+func (s *Server) updateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	variantID, err := strconv.ParseInt(r.PathValue("variantId"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var clientVariant db.ClientVariant
+	if err := decodeJSON(r, &clientVariant); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if err := validateClientVariant(clientVariant); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	variant, err := s.db.UpdateVariant(r.Context(), productID, variantID, clientVariant)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variant)
+}
+
+func (s *Server) deleteVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	variantID, err := strconv.ParseInt(r.PathValue("variantId"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteVariant(r.Context(), productID, variantID); err != nil {
+		writeDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===========================================
+// =================HELPERS===================
+// ===========================================
+
+func validateClientVariant(variant db.ClientVariant) error {
+	var v validator
+	v.check(skuPattern.MatchString(variant.SKU), "sku", "must be 1 to 64 letters, digits, dots, dashes or underscores")
+	if variant.Price != nil {
+		v.price("price", *variant.Price)
+	}
+	if variant.Image != nil {
+		v.imageURL("image", *variant.Image)
+	}
+	return v.err()
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"catalogapi/db"
+	"catalogapi/money"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateVariant(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	tests := []struct {
+		body     string
+		expected int
+	}{
+		{body: `{"sku": "TS-RED-M", "options": {"color": "red", "size": "M"}, "price": {"amount": "21.99", "currency": "USD"}}`, expected: http.StatusCreated},
+		{body: `{"sku": "TS-RED-M"}`, expected: http.StatusConflict},
+		{body: `{"sku": ""}`, expected: http.StatusUnprocessableEntity},
+		{body: `{"sku": "TS-BLUE-M", "price": {"amount": "-1", "currency": "USD"}}`, expected: http.StatusUnprocessableEntity},
+		{body: `{"sku": "TS-BLUE-M", "image": "not a url"}`, expected: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/products/1/variants", bytes.NewBufferString(tt.body))
+		r.SetPathValue("id", "1")
+		w := httptest.NewRecorder()
+		srv.createVariant(w, r)
+		require.Equal(t, tt.expected, w.Code, tt.body)
+	}
+}
+
+func TestGetProductWithVariants(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+
+	price := money.MustParse("24.99", "USD")
+	testVariants := []db.Variant{
+		{ID: 1, ProductID: 1, SKU: "TS-S", Options: map[string]string{"size": "S"}},
+		{ID: 2, ProductID: 1, SKU: "TS-XL", Options: map[string]string{"size": "XL"}, Price: &price},
+	}
+	err = db.PopulateTestData(ctx, database, "product_variants", testVariants)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products/1", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var product db.Product
+	err = json.NewDecoder(w.Body).Decode(&product)
+	require.NoError(t, err)
+	validateProduct(t, product, testProducts[0])
+	require.Len(t, product.Variants, 2)
+	assert.Equal(t, "TS-S", product.Variants[0].SKU)
+	assert.Nil(t, product.Variants[0].Price)
+	assert.Equal(t, "TS-XL", product.Variants[1].SKU)
+	require.NotNil(t, product.Variants[1].Price)
+	assert.Equal(t, price, *product.Variants[1].Price)
+}
+
+func TestPostReviewWithVariant(t *testing.T) {
+	database, cleanup, ctx := setupTestDB(t)
+	defer cleanup()
+	srv := New(database)
+
+	testProducts := []db.Product{
+		{ID: 1, Name: "T-Shirt", Price: money.MustParse("19.99", "USD"), Image: "https://via.placeholder.com/150", Description: "Test Description 1"},
+	}
+	err := db.PopulateTestData(ctx, database, "products", testProducts)
+	require.NoError(t, err)
+	testVariants := []db.Variant{
+		{ID: 1, ProductID: 1, SKU: "TS-S", Options: map[string]string{"size": "S"}},
+	}
+	err = db.PopulateTestData(ctx, database, "product_variants", testVariants)
+	require.NoError(t, err)
+
+	tests := []struct {
+		body     string
+		expected int
+	}{
+		{body: `{"productId": 1, "variantId": 1, "reviewTitle": "Fits", "reviewContent": "Great fit", "stars": 5}`, expected: http.StatusCreated},
+		{body: `{"productId": 1, "variantId": 42, "reviewTitle": "Fits", "reviewContent": "Great fit", "stars": 5}`, expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/reviews", bytes.NewBufferString(tt.body))
+		w := httptest.NewRecorder()
+		authCtx := context.WithValue(r.Context(), userIDKey, "1")
+		srv.postReview(w, r.WithContext(authCtx))
+		require.Equal(t, tt.expected, w.Code, tt.body)
+
+		if tt.expected == http.StatusCreated {
+			var review db.SafeReview
+			err = json.NewDecoder(w.Body).Decode(&review)
+			require.NoError(t, err)
+			require.NotNil(t, review.VariantID)
+			assert.Equal(t, int64(1), *review.VariantID)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "uploads")
+	store := NewLocalStore(dir)
+
+	err := store.Put(ctx, "a.png", strings.NewReader("image data"))
+	require.NoError(t, err)
+
+	blob, err := store.Get(ctx, "a.png")
+	require.NoError(t, err)
+	data, err := io.ReadAll(blob)
+	blob.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "image data", string(data))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	err = store.Delete(ctx, "a.png")
+	require.NoError(t, err)
+
+	_, err = store.Get(ctx, "a.png")
+	require.ErrorIs(t, err, ErrNotFound)
+	err = store.Delete(ctx, "a.png")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStoreInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir())
+
+	for _, key := range []string{"", ".", "..", "../a.png", "a/b.png", `a\b.png`, ".hidden"} {
+		err := store.Put(ctx, key, strings.NewReader("image data"))
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+		_, err = store.Get(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
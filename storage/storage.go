@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore stores opaque blobs under flat keys. Keys are chosen by the
+// caller and a stored blob is never overwritten, which lets clients cache
+// them forever.
+type BlobStore interface {
+	// Put stores the content of r under key
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the blob stored under key, the caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every runs fn once per interval until ctx is cancelled. Errors are logged
+// and do not stop the loop, the next tick simply tries again.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Worker %q started, running every %s\n", name, interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Worker %q stopped\n", name)
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Worker %q failed: %v\n", name, err)
+			}
+		}
+	}
+}